@@ -3,8 +3,13 @@ package config
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/brunoga/go-pipeliner/pipeline"
+	"github.com/brunoga/go-pipeliner/retry"
+	"github.com/brunoga/go-pipeliner/state"
 	"github.com/kylelemons/go-gypsy/yaml"
 
 	pipeliner_modules "github.com/brunoga/go-pipeliner/modules"
@@ -40,6 +45,29 @@ func New(path string) (*Config, error) {
 	return config, nil
 }
 
+// SetStateStore sets the store used by all pipelines to persist state between
+// runs. If fromScratch is true, saved checkpoints are ignored.
+func (c *Config) SetStateStore(stateStore *state.Store, fromScratch bool) {
+	for _, pipeline := range c.pipelines {
+		pipeline.SetStateStore(stateStore, fromScratch)
+	}
+}
+
+// SetDebug enables or disables debug logs for all pipelines.
+func (c *Config) SetDebug(debug bool) {
+	for _, pipeline := range c.pipelines {
+		pipeline.SetDebug(debug)
+	}
+}
+
+// SetExplain sets how items dropped by all pipelines are reported (see
+// pipeline.Pipeline.SetExplain).
+func (c *Config) SetExplain(explain bool, path string) {
+	for _, pipeline := range c.pipelines {
+		pipeline.SetExplain(explain, path)
+	}
+}
+
 func (c *Config) StartPipelines() error {
 	for _, pipeline := range c.pipelines {
 		err := pipeline.Start()
@@ -90,17 +118,26 @@ func configureModule(node yaml.Node, module modules_base.Module) error {
 			continue
 		}
 
+		handled, err := configureGenericParameter(key, configValueNode,
+			module)
+		if err != nil {
+			return err
+		}
+		if handled {
+			continue
+		}
+
 		_, ok := (*parameters)[key]
 		if !ok {
 			return fmt.Errorf("unknown parameter %q", key)
 		}
 
-		configValue, ok := configValueNode.(yaml.Scalar)
-		if !ok {
-			return fmt.Errorf("node has parameter field with invalid type")
+		configValue, err := parameterValue(configValueNode)
+		if err != nil {
+			return err
 		}
 
-		(*parameters)[key] = configValue.String()
+		(*parameters)[key] = configValue
 	}
 
 	err := module.Configure(parameters)
@@ -115,6 +152,193 @@ func configureModule(node yaml.Node, module modules_base.Module) error {
 	return nil
 }
 
+// configureGenericParameter handles parameters that are supported by the
+// generic module implementations instead of by specific modules. It returns
+// true if the given key was handled and a nil error on success or a non-nil
+// error on failure.
+func configureGenericParameter(key string, node yaml.Node,
+	module modules_base.Module) (bool, error) {
+	switch key {
+	case "workers":
+		workersSetter, ok := module.(pipeliner_modules.WorkersSetter)
+		if !ok {
+			return false, nil
+		}
+
+		value, err := scalarValue(node, key)
+		if err != nil {
+			return true, err
+		}
+
+		workers, err := strconv.Atoi(value)
+		if err != nil {
+			return true, fmt.Errorf("invalid workers parameter : %v", err)
+		}
+
+		return true, workersSetter.SetWorkers(workers)
+	case "ordered":
+		orderedSetter, ok := module.(pipeliner_modules.OrderedSetter)
+		if !ok {
+			return false, nil
+		}
+
+		value, err := scalarValue(node, key)
+		if err != nil {
+			return true, err
+		}
+
+		ordered, err := strconv.ParseBool(value)
+		if err != nil {
+			return true, fmt.Errorf("invalid ordered parameter : %v", err)
+		}
+
+		orderedSetter.SetOrdered(ordered)
+
+		return true, nil
+	case "flush_interval":
+		flushIntervalSetter, ok := module.(pipeliner_modules.FlushIntervalSetter)
+		if !ok {
+			return false, nil
+		}
+
+		value, err := scalarValue(node, key)
+		if err != nil {
+			return true, err
+		}
+
+		flushInterval, err := time.ParseDuration(value)
+		if err != nil {
+			return true, fmt.Errorf("invalid flush_interval parameter : %v",
+				err)
+		}
+
+		return true, flushIntervalSetter.SetFlushInterval(flushInterval)
+	case "max_pending":
+		maxPendingSetter, ok := module.(pipeliner_modules.MaxPendingSetter)
+		if !ok {
+			return false, nil
+		}
+
+		value, err := scalarValue(node, key)
+		if err != nil {
+			return true, err
+		}
+
+		maxPending, err := strconv.Atoi(value)
+		if err != nil {
+			return true, fmt.Errorf("invalid max_pending parameter : %v",
+				err)
+		}
+
+		return true, maxPendingSetter.SetMaxPending(maxPending)
+	case "record":
+		recordPathSetter, ok := module.(pipeliner_modules.RecordPathSetter)
+		if !ok {
+			return false, nil
+		}
+
+		value, err := scalarValue(node, key)
+		if err != nil {
+			return true, err
+		}
+
+		recordPathSetter.SetRecordPath(value)
+
+		return true, nil
+	case "fingerprint":
+		fingerprintFieldsSetter, ok := module.(pipeliner_modules.FingerprintFieldsSetter)
+		if !ok {
+			return false, nil
+		}
+
+		value, err := scalarValue(node, key)
+		if err != nil {
+			return true, err
+		}
+
+		var fields []string
+		for _, field := range strings.Split(value, ",") {
+			fields = append(fields, strings.TrimSpace(field))
+		}
+
+		return true, fingerprintFieldsSetter.SetFingerprintFields(fields)
+	case "retry_attempts", "retry_backoff", "retry_max_backoff",
+		"retry_multiplier", "retry_jitter":
+		retrier, ok := module.(pipeliner_modules.Retrier)
+		if !ok {
+			return false, nil
+		}
+
+		value, err := scalarValue(node, key)
+		if err != nil {
+			return true, err
+		}
+
+		return true, configureRetryParameter(key, value,
+			retrier.RetryPolicy())
+	}
+
+	return false, nil
+}
+
+func configureRetryParameter(key, value string, policy *retry.Policy) error {
+	var err error
+
+	switch key {
+	case "retry_attempts":
+		policy.MaxAttempts, err = strconv.Atoi(value)
+	case "retry_backoff":
+		policy.InitialBackoff, err = time.ParseDuration(value)
+	case "retry_max_backoff":
+		policy.MaxBackoff, err = time.ParseDuration(value)
+	case "retry_multiplier":
+		policy.Multiplier, err = strconv.ParseFloat(value, 64)
+	case "retry_jitter":
+		policy.Jitter, err = strconv.ParseFloat(value, 64)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s parameter : %v", key, err)
+	}
+
+	err = policy.Validate()
+	if err != nil {
+		return fmt.Errorf("invalid %s parameter : %v", key, err)
+	}
+
+	return nil
+}
+
+func scalarValue(node yaml.Node, key string) (string, error) {
+	scalar, ok := node.(yaml.Scalar)
+	if !ok {
+		return "", fmt.Errorf("parameter %q has invalid type", key)
+	}
+
+	return scalar.String(), nil
+}
+
+// parameterValue returns the value of a module parameter. Lists of scalars
+// are returned as a single string with one element per line.
+func parameterValue(node yaml.Node) (string, error) {
+	switch typedNode := node.(type) {
+	case yaml.Scalar:
+		return typedNode.String(), nil
+	case yaml.List:
+		values := make([]string, 0, typedNode.Len())
+		for _, elementNode := range typedNode {
+			element, ok := elementNode.(yaml.Scalar)
+			if !ok {
+				return "", fmt.Errorf("node has parameter list " +
+					"with invalid element type")
+			}
+			values = append(values, element.String())
+		}
+		return strings.Join(values, "\n"), nil
+	}
+
+	return "", fmt.Errorf("node has parameter field with invalid type")
+}
+
 func setupModule(node yaml.Node, key string) (modules_base.Module, error) {
 	nameNode, err := yaml.Child(node, ".name")
 	if err != nil || nameNode == nil {
@@ -146,7 +370,8 @@ func setupModule(node yaml.Node, key string) (modules_base.Module, error) {
 
 	err = configureModule(node, module)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("error configuring %s module %q : %v", key,
+			name, err)
 	}
 
 	return module, nil
@@ -221,6 +446,26 @@ func validatePipeline(pipelineNode yaml.Node, key string) (*pipeline.Pipeline, e
 
 	pipeline := pipeline.New(nameNode.(yaml.Scalar).String())
 
+	// Merge configuration is optional.
+	err = processMergeNodes(pipelineNode, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	// Dead-letter destination is optional.
+	deadLetterNode, err := optionalChild(pipelineNode, ".dead_letter")
+	if err != nil {
+		return nil, err
+	}
+	if deadLetterNode != nil {
+		deadLetterPath, err := scalarValue(deadLetterNode, "dead_letter")
+		if err != nil {
+			return nil, err
+		}
+
+		pipeline.SetDeadLetterPath(deadLetterPath)
+	}
+
 	producerNode, err := yaml.Child(pipelineNode, ".producer")
 	if err != nil {
 		return nil, err
@@ -265,6 +510,56 @@ func validatePipeline(pipelineNode yaml.Node, key string) (*pipeline.Pipeline, e
 	return pipeline, nil
 }
 
+func processMergeNodes(pipelineNode yaml.Node, pipeline *pipeline.Pipeline) error {
+	mergeNode, err := optionalChild(pipelineNode, ".merge")
+	if err != nil {
+		return err
+	}
+	if mergeNode == nil {
+		return nil
+	}
+
+	mode, err := scalarValue(mergeNode, "merge")
+	if err != nil {
+		return err
+	}
+
+	window := 0
+
+	windowNode, err := optionalChild(pipelineNode, ".merge_window")
+	if err != nil {
+		return err
+	}
+	if windowNode != nil {
+		windowValue, err := scalarValue(windowNode, "merge_window")
+		if err != nil {
+			return err
+		}
+
+		window, err = strconv.Atoi(windowValue)
+		if err != nil {
+			return fmt.Errorf("invalid merge_window : %v", err)
+		}
+	}
+
+	return pipeline.SetMergeMode(mode, window)
+}
+
+// optionalChild returns the child node at the given path or nil if it does not
+// exist.
+func optionalChild(node yaml.Node, path string) (yaml.Node, error) {
+	child, err := yaml.Child(node, path)
+	if err != nil {
+		if _, ok := err.(*yaml.NodeNotFound); !ok {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+
+	return child, nil
+}
+
 func processListOrMapNode(node yaml.Node, requireList bool,
 	mapFunc func(yaml.Node, string) error) error {
 	switch checkedNode := node.(type) {
@@ -0,0 +1,112 @@
+package datatypes
+
+import (
+	"sync"
+)
+
+// ackState tracks the delivery of an item through all the pipeline branches it
+// was sent to.
+type ackState struct {
+	mutex sync.Mutex
+
+	pending   int
+	err       error
+	delivered bool
+	handlers  []func(error)
+}
+
+func newAckState() *ackState {
+	return &ackState{
+		pending: 1,
+	}
+}
+
+// AddAckHandler adds a function to be called once the item was fully handled
+// (i.e. Ack or Nack was called for every pipeline branch it was sent to). The
+// handler is called with a nil error if all branches acknowledged the item or
+// with the first error passed to Nack otherwise. Handlers are called in the
+// order they were added.
+func (i *PipelineItem) AddAckHandler(handler func(error)) {
+	i.ack.mutex.Lock()
+	defer i.ack.mutex.Unlock()
+
+	i.ack.handlers = append(i.ack.handlers, handler)
+}
+
+// AddPendingAcks increases the number of Ack or Nack calls required for the
+// item to be considered fully handled. This must be called before the item is
+// sent to additional pipeline branches (for example, by a demultiplexer). It
+// does nothing if the item was already fully handled, so ack handlers are never
+// called more than once.
+func (i *PipelineItem) AddPendingAcks(count int) {
+	i.ack.mutex.Lock()
+	defer i.ack.mutex.Unlock()
+
+	if i.ack.pending == 0 {
+		return
+	}
+
+	i.ack.pending += count
+}
+
+// Ack signals that one of the branches the item was sent to delivered it (for
+// example, to a consumer) successfully.
+func (i *PipelineItem) Ack() {
+	i.resolve(nil, true)
+}
+
+// AckDropped signals that one of the branches the item was sent to dropped it
+// on purpose (for example, a processor filtered it out). Like Ack, this counts
+// as successfully handling the item, but it does not mark it as delivered.
+func (i *PipelineItem) AckDropped() {
+	i.resolve(nil, false)
+}
+
+// Nack signals that one of the branches the item was sent to failed to handle
+// it with the given error.
+func (i *PipelineItem) Nack(err error) {
+	i.resolve(err, false)
+}
+
+// Delivered returns true if Ack was called for at least one of the branches
+// the item was sent to. Ack handlers can use it to tell items that were
+// delivered from items that were only dropped.
+func (i *PipelineItem) Delivered() bool {
+	i.ack.mutex.Lock()
+	defer i.ack.mutex.Unlock()
+
+	return i.ack.delivered
+}
+
+func (i *PipelineItem) resolve(err error, delivered bool) {
+	i.ack.mutex.Lock()
+
+	if i.ack.pending == 0 {
+		// Already fully handled.
+		i.ack.mutex.Unlock()
+		return
+	}
+
+	if err != nil && i.ack.err == nil {
+		i.ack.err = err
+	}
+	if delivered {
+		i.ack.delivered = true
+	}
+
+	i.ack.pending--
+	if i.ack.pending > 0 {
+		i.ack.mutex.Unlock()
+		return
+	}
+
+	handlers := i.ack.handlers
+	i.ack.handlers = nil
+	err = i.ack.err
+
+	i.ack.mutex.Unlock()
+
+	for _, handler := range handlers {
+		handler(err)
+	}
+}
@@ -0,0 +1,38 @@
+package datatypes
+
+import (
+	"fmt"
+	"time"
+)
+
+// BatchPayloadId is the payload id used by items that group other items
+// together (see NewBatchPipelineItem).
+const BatchPayloadId = "batch"
+
+// NewBatchPipelineItem creates a new item that groups the given items. The
+// grouped items are stored as a []*PipelineItem payload with id
+// BatchPayloadId. Acknowledging the batch item acknowledges all grouped items
+// (which are delivered if the batch item was).
+func NewBatchPipelineItem(inputGenericId string,
+	items []*PipelineItem) *PipelineItem {
+	batchItem := NewPipelineItem(inputGenericId)
+	batchItem.SetName(fmt.Sprintf("batch of %d items", len(items)))
+	batchItem.SetDate(time.Now())
+	batchItem.AddPayload(BatchPayloadId, items)
+	batchItem.AddAckHandler(func(err error) {
+		delivered := batchItem.Delivered()
+		for _, item := range items {
+			item.resolve(err, delivered)
+		}
+	})
+
+	return batchItem
+}
+
+// GetBatch returns the items grouped by this item and true if this is a batch
+// item or nil and false otherwise.
+func (i *PipelineItem) GetBatch() ([]*PipelineItem, bool) {
+	items, err := GetPayloadAs[[]*PipelineItem](i, BatchPayloadId)
+
+	return items, err == nil
+}
@@ -0,0 +1,283 @@
+package datatypes
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// PayloadCodec encodes and decodes payloads so they can be serialized together
+// with the PipelineItem they are associated with. Encode must return valid
+// JSON.
+type PayloadCodec interface {
+	Encode(payload interface{}) ([]byte, error)
+	Decode(data []byte) (interface{}, error)
+}
+
+var (
+	payloadCodecsMutex sync.RWMutex
+	payloadCodecs      = make(map[string]PayloadCodec)
+)
+
+// RegisterPayloadCodec registers the codec to be used for payloads with the
+// given payloadId. Payloads without a registered codec are not serialized.
+func RegisterPayloadCodec(payloadId string, codec PayloadCodec) error {
+	if codec == nil {
+		return fmt.Errorf("can't register a nil codec")
+	}
+
+	payloadCodecsMutex.Lock()
+	defer payloadCodecsMutex.Unlock()
+
+	_, ok := payloadCodecs[payloadId]
+	if ok {
+		return fmt.Errorf("codec for payload id %q already registered",
+			payloadId)
+	}
+
+	payloadCodecs[payloadId] = codec
+
+	return nil
+}
+
+func getPayloadCodec(payloadId string) PayloadCodec {
+	payloadCodecsMutex.RLock()
+	defer payloadCodecsMutex.RUnlock()
+
+	return payloadCodecs[payloadId]
+}
+
+// jsonPayloadCodec is a PayloadCodec that uses encoding/json.
+type jsonPayloadCodec struct {
+	payloadType reflect.Type
+}
+
+// NewJSONPayloadCodec returns a PayloadCodec that encodes payloads with
+// encoding/json and decodes them to the same type as the given prototype
+// (which can be a pointer).
+func NewJSONPayloadCodec(prototype interface{}) PayloadCodec {
+	return &jsonPayloadCodec{
+		reflect.TypeOf(prototype),
+	}
+}
+
+func (c *jsonPayloadCodec) Encode(payload interface{}) ([]byte, error) {
+	return json.Marshal(payload)
+}
+
+func (c *jsonPayloadCodec) Decode(data []byte) (interface{}, error) {
+	if c.payloadType.Kind() == reflect.Ptr {
+		payload := reflect.New(c.payloadType.Elem())
+		err := json.Unmarshal(data, payload.Interface())
+		if err != nil {
+			return nil, err
+		}
+
+		return payload.Interface(), nil
+	}
+
+	payload := reflect.New(c.payloadType)
+	err := json.Unmarshal(data, payload.Interface())
+	if err != nil {
+		return nil, err
+	}
+
+	return payload.Elem().Interface(), nil
+}
+
+// itemEncodingVersion is the version of the serialized item format.
+const itemEncodingVersion = 1
+
+// encodedItem is the serialized representation of a PipelineItem.
+type encodedItem struct {
+	Version        int                        `json:"version"`
+	Id             string                     `json:"id,omitempty"`
+	Fingerprint    string                     `json:"fingerprint,omitempty"`
+	Origin         *Origin                    `json:"origin,omitempty"`
+	History        []HistoryEntry             `json:"history,omitempty"`
+	InputGenericId string                     `json:"input_generic_id"`
+	Name           string                     `json:"name"`
+	Description    string                     `json:"description"`
+	Date           time.Time                  `json:"date"`
+	NoDate         bool                       `json:"no_date,omitempty"`
+	Urls           []string                   `json:"urls"`
+	Metadata       map[string]json.RawMessage `json:"metadata,omitempty"`
+	Payload        map[string]json.RawMessage `json:"payload,omitempty"`
+}
+
+func (i *PipelineItem) encode() (*encodedItem, error) {
+	encoded := &encodedItem{
+		Version:        itemEncodingVersion,
+		Id:             i.id,
+		Fingerprint:    i.fingerprint,
+		History:        i.GetHistory(),
+		InputGenericId: i.inputGenericId,
+		Name:           i.name,
+		Description:    i.description,
+		Date:           i.date,
+		NoDate:         !i.hasDate,
+		Urls:           make([]string, 0, len(i.urls)),
+	}
+
+	for _, itemUrl := range i.urls {
+		encoded.Urls = append(encoded.Urls, itemUrl.String())
+	}
+
+	origin := i.GetOrigin()
+	if origin != (Origin{}) {
+		encoded.Origin = &origin
+	}
+
+	metadata, err := encodeMetadata(i.GetAllMetadata())
+	if err != nil {
+		return nil, fmt.Errorf("error encoding metadata : %v", err)
+	}
+	encoded.Metadata = metadata
+
+	// Payload ids are sorted so encoding is stable.
+	for _, payloadId := range i.GetPayloadIds() {
+		codec := getPayloadCodec(payloadId)
+		if codec == nil {
+			continue
+		}
+
+		payload, err := i.GetPayload(payloadId)
+		if err != nil {
+			// Removed concurrently.
+			continue
+		}
+
+		data, err := codec.Encode(payload)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding payload %q : %v",
+				payloadId, err)
+		}
+
+		if encoded.Payload == nil {
+			encoded.Payload = make(map[string]json.RawMessage)
+		}
+		encoded.Payload[payloadId] = data
+	}
+
+	return encoded, nil
+}
+
+func (i *PipelineItem) decode(encoded *encodedItem) error {
+	if encoded.Version > itemEncodingVersion {
+		return fmt.Errorf("unsupported item encoding version %d",
+			encoded.Version)
+	}
+
+	decoded := NewPipelineItem(encoded.InputGenericId)
+	if encoded.Id != "" {
+		decoded.id = encoded.Id
+	}
+	decoded.fingerprint = encoded.Fingerprint
+	if encoded.Origin != nil {
+		decoded.lineage.origin = *encoded.Origin
+	}
+	decoded.lineage.history = encoded.History
+	decoded.name = encoded.Name
+	decoded.description = encoded.Description
+	decoded.date = encoded.Date
+	decoded.hasDate = !encoded.NoDate
+
+	for _, itemUrl := range encoded.Urls {
+		parsedUrl, err := url.Parse(itemUrl)
+		if err != nil {
+			return err
+		}
+
+		decoded.urls = append(decoded.urls, parsedUrl)
+	}
+
+	metadata, err := decodeMetadata(encoded.Metadata)
+	if err != nil {
+		return fmt.Errorf("error decoding metadata : %v", err)
+	}
+	decoded.metadata.values = metadata
+
+	for payloadId, data := range encoded.Payload {
+		codec := getPayloadCodec(payloadId)
+		if codec == nil {
+			// Unknown payload. Skip it.
+			continue
+		}
+
+		payload, err := codec.Decode(data)
+		if err != nil {
+			return fmt.Errorf("error decoding payload %q : %v",
+				payloadId, err)
+		}
+
+		decoded.payload.values[payloadId] = payload
+	}
+
+	*i = *decoded
+
+	return nil
+}
+
+// MarshalJSON returns the JSON encoding of the item. Only payloads with a
+// registered codec (see RegisterPayloadCodec) are included. This satisfies the
+// json.Marshaler interface.
+func (i *PipelineItem) MarshalJSON() ([]byte, error) {
+	encoded, err := i.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(encoded)
+}
+
+// UnmarshalJSON sets the item to the one encoded in the given JSON data. This
+// satisfies the json.Unmarshaler interface.
+func (i *PipelineItem) UnmarshalJSON(data []byte) error {
+	encoded := &encodedItem{}
+	err := json.Unmarshal(data, encoded)
+	if err != nil {
+		return err
+	}
+
+	return i.decode(encoded)
+}
+
+// GobEncode returns the binary encoding of the item. Only payloads with a
+// registered codec (see RegisterPayloadCodec) are included. This satisfies the
+// gob.GobEncoder interface.
+func (i *PipelineItem) GobEncode() ([]byte, error) {
+	encoded, err := i.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	err = gob.NewEncoder(&buffer).Encode(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// GobDecode sets the item to the one encoded in the given binary data. This
+// satisfies the gob.GobDecoder interface.
+func (i *PipelineItem) GobDecode(data []byte) error {
+	encoded := &encodedItem{}
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(encoded)
+	if err != nil {
+		return err
+	}
+
+	return i.decode(encoded)
+}
+
+func init() {
+	RegisterPayloadCodec(BatchPayloadId,
+		NewJSONPayloadCodec([]*PipelineItem{}))
+}
@@ -0,0 +1,194 @@
+package datatypes
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Actions recorded in an item history.
+const (
+	ActionProduced = "produced"
+	ActionPassed   = "passed"
+	ActionModified = "modified"
+	ActionDropped  = "dropped"
+	ActionConsumed = "consumed"
+)
+
+// Fields that can be used to compute an item fingerprint.
+const (
+	FingerprintFieldName        = "name"
+	FingerprintFieldDescription = "description"
+	FingerprintFieldDate        = "date"
+	FingerprintFieldUrls        = "urls"
+)
+
+// DefaultFingerprintFields are the fields used to compute an item fingerprint
+// when no fields are given.
+var DefaultFingerprintFields = []string{
+	FingerprintFieldName,
+	FingerprintFieldUrls,
+}
+
+// AllFingerprintFields are all the fields that can be used to compute an item
+// fingerprint.
+var AllFingerprintFields = []string{
+	FingerprintFieldName,
+	FingerprintFieldDescription,
+	FingerprintFieldDate,
+	FingerprintFieldUrls,
+}
+
+// Origin identifies where an item entered the pipeline.
+type Origin struct {
+	Pipeline   string `json:"pipeline"`
+	GenericId  string `json:"generic_id"`
+	SpecificId string `json:"specific_id"`
+}
+
+// String returns a string representation of the origin. This satisfies the
+// fmt.Stringer interface.
+func (o Origin) String() string {
+	return o.Pipeline + "/" + o.GenericId + "/" + o.SpecificId
+}
+
+// HistoryEntry records an action taken on an item by a pipeline node.
+type HistoryEntry struct {
+	Time       time.Time `json:"time"`
+	GenericId  string    `json:"generic_id"`
+	SpecificId string    `json:"specific_id"`
+	Action     string    `json:"action"`
+	Detail     string    `json:"detail,omitempty"`
+}
+
+// String returns a string representation of the entry. This satisfies the
+// fmt.Stringer interface.
+func (e HistoryEntry) String() string {
+	entry := fmt.Sprintf("%s %s by %s/%s", e.Time.Format(time.RFC3339Nano),
+		e.Action, e.GenericId, e.SpecificId)
+	if e.Detail != "" {
+		entry += " (" + e.Detail + ")"
+	}
+
+	return entry
+}
+
+// lineage holds the origin and history of an item.
+type lineage struct {
+	mutex sync.Mutex
+
+	origin  Origin
+	history []HistoryEntry
+}
+
+// newItemId returns a new random item id.
+func newItemId() string {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		// Extremely unlikely. Fallback to something that is at least
+		// unique in this process.
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+
+	return hex.EncodeToString(id)
+}
+
+// GetId returns the unique id for this item.
+func (i *PipelineItem) GetId() string {
+	return i.id
+}
+
+// SetOrigin sets where this item entered the pipeline.
+func (i *PipelineItem) SetOrigin(origin Origin) {
+	i.lineage.mutex.Lock()
+	defer i.lineage.mutex.Unlock()
+
+	i.lineage.origin = origin
+}
+
+// GetOrigin returns where this item entered the pipeline.
+func (i *PipelineItem) GetOrigin() Origin {
+	i.lineage.mutex.Lock()
+	defer i.lineage.mutex.Unlock()
+
+	return i.lineage.origin
+}
+
+// AddHistory appends the given entry to the item history.
+func (i *PipelineItem) AddHistory(entry HistoryEntry) {
+	i.lineage.mutex.Lock()
+	defer i.lineage.mutex.Unlock()
+
+	i.lineage.history = append(i.lineage.history, entry)
+}
+
+// GetHistory returns a copy of the item history.
+func (i *PipelineItem) GetHistory() []HistoryEntry {
+	i.lineage.mutex.Lock()
+	defer i.lineage.mutex.Unlock()
+
+	history := make([]HistoryEntry, len(i.lineage.history))
+	copy(history, i.lineage.history)
+
+	return history
+}
+
+// Lineage returns a human readable description of the item id, origin and
+// history.
+func (i *PipelineItem) Lineage() string {
+	lines := []string{
+		fmt.Sprintf("item %s %q from %s", i.id, i.GetName(), i.GetOrigin()),
+	}
+
+	for _, entry := range i.GetHistory() {
+		lines = append(lines, "  "+entry.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// ComputeFingerprint returns a hash of the given fields of the item (see the
+// FingerprintField* constants). If no fields are given,
+// DefaultFingerprintFields are used.
+func (i *PipelineItem) ComputeFingerprint(fields ...string) (string, error) {
+	if len(fields) == 0 {
+		fields = DefaultFingerprintFields
+	}
+
+	hash := sha256.New()
+	for _, field := range fields {
+		switch field {
+		case FingerprintFieldName:
+			fmt.Fprintf(hash, "name:%q\n", i.GetName())
+		case FingerprintFieldDescription:
+			fmt.Fprintf(hash, "description:%q\n", i.GetDescription())
+		case FingerprintFieldDate:
+			fmt.Fprintf(hash, "date:%q\n",
+				i.GetDate().UTC().Format(time.RFC3339Nano))
+		case FingerprintFieldUrls:
+			for _, itemUrl := range i.GetUrls() {
+				fmt.Fprintf(hash, "url:%q\n", itemUrl.String())
+			}
+		default:
+			return "", fmt.Errorf("unknown fingerprint field %q", field)
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// SetFingerprint sets the fingerprint for this item (usually computed with
+// ComputeFingerprint when the item is created).
+func (i *PipelineItem) SetFingerprint(fingerprint string) {
+	i.fingerprint = fingerprint
+}
+
+// GetFingerprint returns the fingerprint for this item.
+func (i *PipelineItem) GetFingerprint() string {
+	return i.fingerprint
+}
@@ -0,0 +1,393 @@
+package datatypes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Well-known metadata keys. Modules should use these (and the typed accessors
+// below) whenever the information they want to store matches one of them, so
+// other modules can find it.
+const (
+	// MetadataSize is the size of the item content, in bytes (int64).
+	MetadataSize = "size"
+
+	// MetadataContentType is the MIME type of the item content (string).
+	MetadataContentType = "content_type"
+
+	// MetadataAuthor is the author of the item (string).
+	MetadataAuthor = "author"
+
+	// MetadataTags are free-form tags associated with the item
+	// ([]string).
+	MetadataTags = "tags"
+
+	// MetadataCategories are the categories the item belongs to
+	// ([]string).
+	MetadataCategories = "categories"
+
+	// MetadataGuid is a globally unique identifier for the item content, as
+	// given by its source (string).
+	MetadataGuid = "guid"
+)
+
+var (
+	metadataTypesMutex sync.RWMutex
+	metadataTypes      = map[string]reflect.Type{
+		MetadataSize:        reflect.TypeOf(int64(0)),
+		MetadataContentType: reflect.TypeOf(""),
+		MetadataAuthor:      reflect.TypeOf(""),
+		MetadataTags:        reflect.TypeOf([]string(nil)),
+		MetadataCategories:  reflect.TypeOf([]string(nil)),
+		MetadataGuid:        reflect.TypeOf(""),
+	}
+)
+
+// RegisterMetadataType registers the type of the values stored with the given
+// metadata key (the type of the given example value). Registered values are
+// decoded to that type instead of the generic JSON types (for example, so
+// time.Time values are not decoded as strings).
+func RegisterMetadataType(key string, example interface{}) error {
+	if example == nil {
+		return fmt.Errorf("can't register a nil metadata type")
+	}
+
+	metadataTypesMutex.Lock()
+	defer metadataTypesMutex.Unlock()
+
+	_, ok := metadataTypes[key]
+	if ok {
+		return fmt.Errorf("type for metadata key %q already registered", key)
+	}
+
+	metadataTypes[key] = reflect.TypeOf(example)
+
+	return nil
+}
+
+func getMetadataType(key string) reflect.Type {
+	metadataTypesMutex.RLock()
+	defer metadataTypesMutex.RUnlock()
+
+	return metadataTypes[key]
+}
+
+// Metadata is a set of typed values associated with a PipelineItem. Unlike
+// payloads, metadata values can be freely overwritten and merged.
+type Metadata map[string]interface{}
+
+// metadataState holds the item metadata.
+type metadataState struct {
+	mutex sync.RWMutex
+
+	values Metadata
+}
+
+func newMetadataState() *metadataState {
+	return &metadataState{
+		values: make(Metadata),
+	}
+}
+
+// SetMetadata sets the metadata value for the given key, overwriting any
+// existing value.
+func (i *PipelineItem) SetMetadata(key string, value interface{}) {
+	i.metadata.mutex.Lock()
+	defer i.metadata.mutex.Unlock()
+
+	i.metadata.values[key] = value
+	i.modified()
+}
+
+// GetMetadata returns the metadata value for the given key and true if it
+// exists or nil and false otherwise.
+func (i *PipelineItem) GetMetadata(key string) (interface{}, bool) {
+	i.metadata.mutex.RLock()
+	defer i.metadata.mutex.RUnlock()
+
+	value, ok := i.metadata.values[key]
+
+	return value, ok
+}
+
+// DeleteMetadata removes the metadata value for the given key, if any.
+func (i *PipelineItem) DeleteMetadata(key string) {
+	i.metadata.mutex.Lock()
+	defer i.metadata.mutex.Unlock()
+
+	delete(i.metadata.values, key)
+	i.modified()
+}
+
+// GetMetadataKeys returns the sorted list of metadata keys set for this item.
+func (i *PipelineItem) GetMetadataKeys() []string {
+	i.metadata.mutex.RLock()
+	defer i.metadata.mutex.RUnlock()
+
+	keys := make([]string, 0, len(i.metadata.values))
+	for key := range i.metadata.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// GetAllMetadata returns a copy of all metadata values set for this item.
+func (i *PipelineItem) GetAllMetadata() Metadata {
+	i.metadata.mutex.RLock()
+	defer i.metadata.mutex.RUnlock()
+
+	values := make(Metadata, len(i.metadata.values))
+	for key, value := range i.metadata.values {
+		values[key] = value
+	}
+
+	return values
+}
+
+// MergeMetadata merges the given values into the item metadata. []string
+// values are merged with existing []string values (duplicates are removed).
+// All other values overwrite existing ones.
+func (i *PipelineItem) MergeMetadata(values Metadata) {
+	i.metadata.mutex.Lock()
+	defer i.metadata.mutex.Unlock()
+
+	for key, value := range values {
+		newStrings, ok := value.([]string)
+		if ok {
+			oldStrings, _ := i.metadata.values[key].([]string)
+			i.metadata.values[key] = mergeStrings(oldStrings, newStrings)
+			continue
+		}
+
+		i.metadata.values[key] = value
+	}
+
+	i.modified()
+}
+
+// GetMetadataString returns the metadata value for the given key and true if
+// it exists and is a string or an empty string and false otherwise.
+func (i *PipelineItem) GetMetadataString(key string) (string, bool) {
+	value, ok := i.GetMetadata(key)
+	if !ok {
+		return "", false
+	}
+
+	stringValue, ok := value.(string)
+
+	return stringValue, ok
+}
+
+// GetMetadataInt64 returns the metadata value for the given key and true if it
+// exists and is an integer or zero and false otherwise.
+func (i *PipelineItem) GetMetadataInt64(key string) (int64, bool) {
+	value, ok := i.GetMetadata(key)
+	if !ok {
+		return 0, false
+	}
+
+	switch typedValue := value.(type) {
+	case int64:
+		return typedValue, true
+	case int:
+		return int64(typedValue), true
+	case int32:
+		return int64(typedValue), true
+	}
+
+	return 0, false
+}
+
+// GetMetadataStrings returns a copy of the metadata value for the given key and
+// true if it exists and is a []string or nil and false otherwise.
+func (i *PipelineItem) GetMetadataStrings(key string) ([]string, bool) {
+	value, ok := i.GetMetadata(key)
+	if !ok {
+		return nil, false
+	}
+
+	stringsValue, ok := value.([]string)
+	if !ok {
+		return nil, false
+	}
+
+	return append([]string(nil), stringsValue...), true
+}
+
+// SetSize sets the size of the item content, in bytes.
+func (i *PipelineItem) SetSize(size int64) {
+	i.SetMetadata(MetadataSize, size)
+}
+
+// GetSize returns the size of the item content, in bytes, and true if it is
+// known or zero and false otherwise.
+func (i *PipelineItem) GetSize() (int64, bool) {
+	return i.GetMetadataInt64(MetadataSize)
+}
+
+// SetContentType sets the MIME type of the item content.
+func (i *PipelineItem) SetContentType(contentType string) {
+	i.SetMetadata(MetadataContentType, contentType)
+}
+
+// GetContentType returns the MIME type of the item content and true if it is
+// known or an empty string and false otherwise.
+func (i *PipelineItem) GetContentType() (string, bool) {
+	return i.GetMetadataString(MetadataContentType)
+}
+
+// SetAuthor sets the author of the item.
+func (i *PipelineItem) SetAuthor(author string) {
+	i.SetMetadata(MetadataAuthor, author)
+}
+
+// GetAuthor returns the author of the item and true if it is known or an empty
+// string and false otherwise.
+func (i *PipelineItem) GetAuthor() (string, bool) {
+	return i.GetMetadataString(MetadataAuthor)
+}
+
+// SetGuid sets the globally unique identifier for the item content.
+func (i *PipelineItem) SetGuid(guid string) {
+	i.SetMetadata(MetadataGuid, guid)
+}
+
+// GetGuid returns the globally unique identifier for the item content and true
+// if it is known or an empty string and false otherwise.
+func (i *PipelineItem) GetGuid() (string, bool) {
+	return i.GetMetadataString(MetadataGuid)
+}
+
+// AddTags adds the given tags to the item (duplicates are ignored).
+func (i *PipelineItem) AddTags(tags ...string) {
+	i.MergeMetadata(Metadata{MetadataTags: tags})
+}
+
+// GetTags returns the tags associated with the item.
+func (i *PipelineItem) GetTags() []string {
+	tags, _ := i.GetMetadataStrings(MetadataTags)
+
+	return tags
+}
+
+// AddCategories adds the given categories to the item (duplicates are
+// ignored).
+func (i *PipelineItem) AddCategories(categories ...string) {
+	i.MergeMetadata(Metadata{MetadataCategories: categories})
+}
+
+// GetCategories returns the categories the item belongs to.
+func (i *PipelineItem) GetCategories() []string {
+	categories, _ := i.GetMetadataStrings(MetadataCategories)
+
+	return categories
+}
+
+func mergeStrings(a, b []string) []string {
+	merged := make([]string, 0, len(a)+len(b))
+	seen := make(map[string]bool, len(a)+len(b))
+	for _, value := range append(append([]string(nil), a...), b...) {
+		if seen[value] {
+			continue
+		}
+
+		seen[value] = true
+		merged = append(merged, value)
+	}
+
+	return merged
+}
+
+// encodeMetadata returns the JSON encoding of each metadata value.
+func encodeMetadata(values Metadata) (map[string]json.RawMessage, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	encoded := make(map[string]json.RawMessage, len(values))
+	for key, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+
+		encoded[key] = data
+	}
+
+	return encoded, nil
+}
+
+// decodeMetadata decodes metadata values encoded with encodeMetadata. Values
+// for well-known keys and keys registered with RegisterMetadataType are decoded
+// to their expected types. Other integer numbers are decoded as int64, other
+// numbers as float64 and arrays of strings as []string.
+func decodeMetadata(encoded map[string]json.RawMessage) (Metadata, error) {
+	values := make(Metadata, len(encoded))
+	for key, data := range encoded {
+		if valueType := getMetadataType(key); valueType != nil {
+			value := reflect.New(valueType)
+			err := json.Unmarshal(data, value.Interface())
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for %q : %v", key,
+					err)
+			}
+
+			values[key] = value.Elem().Interface()
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+
+		var value interface{}
+		err := decoder.Decode(&value)
+		if err != nil {
+			return nil, err
+		}
+
+		values[key] = normalizeJSONValue(value)
+	}
+
+	return values, nil
+}
+
+func normalizeJSONValue(value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case json.Number:
+		intValue, err := typedValue.Int64()
+		if err == nil {
+			return intValue
+		}
+
+		floatValue, _ := typedValue.Float64()
+		return floatValue
+	case []interface{}:
+		stringValues := make([]string, 0, len(typedValue))
+		for _, element := range typedValue {
+			stringValue, ok := element.(string)
+			if !ok {
+				break
+			}
+			stringValues = append(stringValues, stringValue)
+		}
+		if len(stringValues) == len(typedValue) {
+			return stringValues
+		}
+
+		for index, element := range typedValue {
+			typedValue[index] = normalizeJSONValue(element)
+		}
+	case map[string]interface{}:
+		for key, element := range typedValue {
+			typedValue[key] = normalizeJSONValue(element)
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,124 @@
+package datatypes
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// payloadState holds the item payloads.
+type payloadState struct {
+	mutex sync.RWMutex
+
+	values PayloadMap
+}
+
+func newPayloadState() *payloadState {
+	return &payloadState{
+		values: make(PayloadMap),
+	}
+}
+
+// AddPayload adds the given payload and associates it with the given payloadId.
+// It returns a nil error in case of success or a non-nil error otherwise.
+func (i *PipelineItem) AddPayload(payloadId string, payload interface{}) error {
+	i.payload.mutex.Lock()
+	defer i.payload.mutex.Unlock()
+
+	_, ok := i.payload.values[payloadId]
+	if ok {
+		return fmt.Errorf("payload with id %q already exists", payloadId)
+	}
+
+	i.payload.values[payloadId] = payload
+	i.modified()
+
+	return nil
+}
+
+// ReplacePayload associates the given payload with the given payloadId,
+// replacing any existing payload with the same id.
+func (i *PipelineItem) ReplacePayload(payloadId string, payload interface{}) {
+	i.payload.mutex.Lock()
+	defer i.payload.mutex.Unlock()
+
+	i.payload.values[payloadId] = payload
+	i.modified()
+}
+
+// RemovePayload removes the payload associated with the given payloadId. It
+// returns true if a payload was removed or false if it did not exist.
+func (i *PipelineItem) RemovePayload(payloadId string) bool {
+	i.payload.mutex.Lock()
+	defer i.payload.mutex.Unlock()
+
+	_, ok := i.payload.values[payloadId]
+	if ok {
+		delete(i.payload.values, payloadId)
+		i.modified()
+	}
+
+	return ok
+}
+
+// GetPayload returns the payload associated with the given payloadId on success
+// or a non-nil error in the case of failure.
+func (i *PipelineItem) GetPayload(payloadId string) (interface{}, error) {
+	i.payload.mutex.RLock()
+	defer i.payload.mutex.RUnlock()
+
+	data, ok := i.payload.values[payloadId]
+	if !ok {
+		return nil, fmt.Errorf("payload with id %q does not exist", payloadId)
+	}
+
+	return data, nil
+}
+
+// HasPayload returns true if there is a payload associated with the given
+// payloadId.
+func (i *PipelineItem) HasPayload(payloadId string) bool {
+	i.payload.mutex.RLock()
+	defer i.payload.mutex.RUnlock()
+
+	_, ok := i.payload.values[payloadId]
+
+	return ok
+}
+
+// GetPayloadIds returns the sorted list of payload ids associated with this
+// item.
+func (i *PipelineItem) GetPayloadIds() []string {
+	i.payload.mutex.RLock()
+	defer i.payload.mutex.RUnlock()
+
+	payloadIds := make([]string, 0, len(i.payload.values))
+	for payloadId := range i.payload.values {
+		payloadIds = append(payloadIds, payloadId)
+	}
+	sort.Strings(payloadIds)
+
+	return payloadIds
+}
+
+// GetPayloadAs returns the payload associated with the given payloadId in the
+// given item as a T. It returns a non-nil error if there is no such payload or
+// if it is not a T. T can also be an interface type (for example, os.FileInfo)
+// implemented by the payload.
+func GetPayloadAs[T any](i *PipelineItem, payloadId string) (T, error) {
+	var typedPayload T
+
+	payload, err := i.GetPayload(payloadId)
+	if err != nil {
+		return typedPayload, err
+	}
+
+	typedPayload, ok := payload.(T)
+	if !ok {
+		return typedPayload, fmt.Errorf("payload with id %q is a %T, not a %v",
+			payloadId, payload, reflect.TypeOf((*T)(nil)).Elem())
+	}
+
+	return typedPayload, nil
+}
@@ -3,6 +3,7 @@ package datatypes
 import (
 	"fmt"
 	"net/url"
+	"sync/atomic"
 	"time"
 )
 
@@ -12,32 +13,61 @@ type PayloadMap map[string]interface{}
 
 // PipelineItem represents an item that is traversing the pipeline.
 type PipelineItem struct {
+	// Accessed atomically. Kept first so it is 64-bit aligned.
+	revision uint64
+
+	id          string
+	fingerprint string
+
 	inputGenericId string
 
 	name        string
 	description string
 
-	date time.Time
+	date    time.Time
+	hasDate bool
 
 	urls []*url.URL
 
-	payload PayloadMap
+	payload  *payloadState
+	metadata *metadataState
+	ack      *ackState
+	lineage  *lineage
 }
 
 // NewPipelineItem creates a new item with the given inputGenericId (i.e. the
 // generic id of the plugin that inserted it in the pipeline). The returned
-// PipelineItem has all fields initialized with default values.
+// PipelineItem has a new unique id and all other fields initialized with
+// default values.
 func NewPipelineItem(inputGenericId string) *PipelineItem {
 	return &PipelineItem{
+		0,
+		newItemId(),
+		"",
 		inputGenericId,
 		"",
 		"",
 		time.Now(),
+		false,
 		make([]*url.URL, 0),
-		make(PayloadMap),
+		newPayloadState(),
+		newMetadataState(),
+		newAckState(),
+		&lineage{},
 	}
 }
 
+// Revision returns a number that changes whenever the item name, description,
+// date, URLs, metadata or payloads are changed. Comparing revisions is a cheap
+// way to find out if an item was modified.
+func (i *PipelineItem) Revision() uint64 {
+	return atomic.LoadUint64(&i.revision)
+}
+
+func (i *PipelineItem) modified() {
+	atomic.AddUint64(&i.revision, 1)
+}
+
 // GetInputGenericId returns the generic id for the input that created this
 // item.
 func (i *PipelineItem) GetInputGenericId() string {
@@ -48,6 +78,7 @@ func (i *PipelineItem) GetInputGenericId() string {
 // item, returning the index of the item just added.
 func (i *PipelineItem) AddUrl(itemUrl *url.URL) int {
 	i.urls = append(i.urls, itemUrl)
+	i.modified()
 	// TODO(bga): This is race condition prone.
 	return len(i.urls) - 1
 }
@@ -74,9 +105,31 @@ func (i *PipelineItem) GetUrl(index int) (*url.URL, error) {
 	return i.urls[index], nil
 }
 
+// SetUrl replaces the URL at the given index, or returns an error in case the
+// index is not valid.
+func (i *PipelineItem) SetUrl(index int, itemUrl *url.URL) error {
+	if index > (len(i.urls)-1) || index < 0 {
+		return fmt.Errorf("index out of bounds")
+	}
+
+	i.urls[index] = itemUrl
+	i.modified()
+
+	return nil
+}
+
+// GetUrls returns all URLs associated with this item.
+func (i *PipelineItem) GetUrls() []*url.URL {
+	urls := make([]*url.URL, len(i.urls))
+	copy(urls, i.urls)
+
+	return urls
+}
+
 // SetName sets the name for the item.
 func (i *PipelineItem) SetName(itemName string) {
 	i.name = itemName
+	i.modified()
 }
 
 // GetName returns the name for this item.
@@ -87,6 +140,7 @@ func (i *PipelineItem) GetName() string {
 // SetDescription sets the description for the item.
 func (i *PipelineItem) SetDescription(itemDescription string) {
 	i.description = itemDescription
+	i.modified()
 }
 
 // GetDescription returns the description for this item.
@@ -97,35 +151,19 @@ func (i *PipelineItem) GetDescription() string {
 // SetDate sets the date for the item.
 func (i *PipelineItem) SetDate(itemDate time.Time) {
 	i.date = itemDate
+	i.hasDate = !itemDate.IsZero()
+	i.modified()
 }
 
-// GetDate returns the date for this item.
+// GetDate returns the date for this item. If no date was set, this is the
+// time the item was created.
 func (i *PipelineItem) GetDate() time.Time {
 	return i.date
 }
 
-// AddPayload adds the given payload and associates it with the given payloadId.
-// It returns a nil error in case of success or a non-nil error otherwise.
-func (i *PipelineItem) AddPayload(payloadId string, payload interface{}) error {
-	_, ok := i.payload[payloadId]
-	if ok {
-		return fmt.Errorf("payload with id %q already exists", payloadId)
-	}
-
-	i.payload[payloadId] = payload
-
-	return nil
-}
-
-// GetPayload returns the payload associated with the given payloadId on success
-// or a non-nil error in the case of failure.
-func (i *PipelineItem) GetPayload(payloadId string) (interface{}, error) {
-	data, ok := i.payload[payloadId]
-	if !ok {
-		return nil, fmt.Errorf("payload with id %q does not exist", payloadId)
-	}
-
-	return data, nil
+// HasDate returns true if a (non-zero) date was set for this item.
+func (i *PipelineItem) HasDate() bool {
+	return i.hasDate
 }
 
 // String returns a string representation of the item. This satisfies the
@@ -0,0 +1,98 @@
+package deadletter
+
+import (
+	"time"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+	"github.com/brunoga/go-pipeliner/jsonlines"
+
+	base_modules "gopkg.in/brunoga/go-modules.v1"
+)
+
+// Entry represents an item that could not be handled by a module.
+type Entry struct {
+	Module base_modules.Module
+	Item   *datatypes.PipelineItem
+	Err    error
+	Time   time.Time
+}
+
+// NewEntry creates a new Entry for the given item that failed in the given
+// module with the given error.
+func NewEntry(module base_modules.Module, item *datatypes.PipelineItem,
+	err error) *Entry {
+	return &Entry{
+		module,
+		item,
+		err,
+		time.Now(),
+	}
+}
+
+// Reporter is implemented by nodes that can report items they failed to
+// handle.
+type Reporter interface {
+	SetDeadLetterChannel(chan<- *Entry)
+}
+
+// Record is the representation of an Entry as written to a dead-letter file.
+type Record struct {
+	Time       time.Time               `json:"time"`
+	GenericId  string                  `json:"generic_id"`
+	SpecificId string                  `json:"specific_id"`
+	Error      string                  `json:"error"`
+	Item       *datatypes.PipelineItem `json:"item"`
+}
+
+// NewRecord creates a new Record from the given Entry.
+func NewRecord(entry *Entry) *Record {
+	record := &Record{
+		Time:       entry.Time,
+		GenericId:  entry.Module.GenericId(),
+		SpecificId: entry.Module.SpecificId(),
+		Item:       entry.Item,
+	}
+
+	if entry.Err != nil {
+		record.Error = entry.Err.Error()
+	}
+
+	return record
+}
+
+// FileWriter appends dead-letter records to a file, one JSON object per line.
+type FileWriter struct {
+	writer *jsonlines.Writer
+}
+
+// NewFileWriter opens (creating it if needed) the file at the given path for
+// appending dead-letter records.
+func NewFileWriter(path string) (*FileWriter, error) {
+	writer, err := jsonlines.Append(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileWriter{writer}, nil
+}
+
+// Write appends the given entry to the file.
+func (w *FileWriter) Write(entry *Entry) error {
+	return w.writer.Write(NewRecord(entry))
+}
+
+// Close closes the underlying file.
+func (w *FileWriter) Close() error {
+	return w.writer.Close()
+}
+
+// ReadFile calls recordFunc for each record in the dead-letter file at the
+// given path, in the order they were written. It stops at the first error
+// returned by recordFunc.
+func ReadFile(path string, recordFunc func(*Record) error) error {
+	return jsonlines.ReadFile(path, func() interface{} {
+		return &Record{}
+	}, func(value interface{}) error {
+		return recordFunc(value.(*Record))
+	})
+}
@@ -0,0 +1,102 @@
+package explain
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+
+	base_modules "gopkg.in/brunoga/go-modules.v1"
+)
+
+// Entry represents an item that was dropped from a pipeline.
+type Entry struct {
+	Module base_modules.Module
+	Item   *datatypes.PipelineItem
+	Reason string
+	Time   time.Time
+}
+
+// NewEntry creates a new Entry for the given item that was dropped by the
+// given module for the given reason.
+func NewEntry(module base_modules.Module, item *datatypes.PipelineItem,
+	reason string) *Entry {
+	return &Entry{
+		module,
+		item,
+		reason,
+		time.Now(),
+	}
+}
+
+// Reporter is implemented by nodes that can report items they dropped.
+type Reporter interface {
+	SetDropChannel(chan<- *Entry)
+}
+
+// record is the representation of an Entry as written to an explain file.
+type record struct {
+	Time       time.Time               `json:"time"`
+	Pipeline   string                  `json:"pipeline"`
+	GenericId  string                  `json:"generic_id"`
+	SpecificId string                  `json:"specific_id"`
+	Reason     string                  `json:"reason"`
+	Item       *datatypes.PipelineItem `json:"item"`
+}
+
+// WriteText writes a human readable description of the given entries, dropped
+// in the given pipeline, to the given writer.
+func WriteText(writer io.Writer, pipeline string, entries []*Entry) error {
+	_, err := fmt.Fprintf(writer, "\n** Pipeline %q dropped %d items:\n\n",
+		pipeline, len(entries))
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		reason := entry.Reason
+		if reason == "" {
+			reason = "no reason given"
+		}
+
+		_, err = fmt.Fprintf(writer, "%q dropped by %s/%s : %s\n",
+			entry.Item.GetName(), entry.Module.GenericId(),
+			entry.Module.SpecificId(), reason)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AppendFile appends the given entries, dropped in the given pipeline, to the
+// file at the given path, one JSON object per line.
+func AppendFile(path, pipeline string, entries []*Entry) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND,
+		0644)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(file)
+	for _, entry := range entries {
+		err = encoder.Encode(&record{
+			Time:       entry.Time,
+			Pipeline:   pipeline,
+			GenericId:  entry.Module.GenericId(),
+			SpecificId: entry.Module.SpecificId(),
+			Reason:     entry.Reason,
+			Item:       entry.Item,
+		})
+		if err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,130 @@
+// Package expr implements a small expression language that is evaluated
+// against pipeline items. For example:
+//
+//	date > now - 24h && name matches "(?i)linux" && size < 4GB
+//
+// Expressions can reference item fields (name, description, date, url, urls,
+// id, fingerprint and input), well-known metadata fields (size, content_type,
+// author, tags, categories and guid), any metadata value (metadata.<key>) and
+// payload values (payload.<id>[.<field>...]). Fields that are not set
+// evaluate to null.
+//
+// Literals are numbers (optionally with a size suffix like 700MB or 4GiB),
+// durations (30m, 1h30m, 7d, 2w), strings ("double quoted" with escapes or
+// `back quoted` without them), lists ([1, 2]), true, false, null and now.
+//
+// Supported operators are ||, && and ! (or, and and not), comparisons (==,
+// !=, <, <=, > and >=), + and - (for numbers, strings, times and durations)
+// and matches (regular expressions), contains, startswith, endswith and in.
+// Comparisons with null values are false (except for == and !=). Times can
+// be compared with strings in RFC 3339 or "2006-01-02" formats.
+//
+// Functions lower(), upper(), trim(), len() and host() (the host name in a
+// URL) are also available.
+package expr
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+)
+
+// Error is an error in an expression. It points to the position in the
+// expression where the error was detected.
+type Error struct {
+	Expression string
+	Column     int
+	Message    string
+}
+
+func newError(column int, format string, a ...interface{}) *Error {
+	return &Error{
+		Column:  column,
+		Message: fmt.Sprintf(format, a...),
+	}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at column %d of expression %q", e.Message,
+		e.Column, e.Expression)
+}
+
+// Expression is a compiled expression.
+type Expression struct {
+	source string
+	root   node
+}
+
+// Compile parses the given source and returns the compiled expression or a
+// non-nil *Error in case it is not valid.
+func Compile(source string) (*Expression, error) {
+	root, err := parse(source)
+	if err != nil {
+		return nil, withSource(err, source)
+	}
+
+	return &Expression{source, root}, nil
+}
+
+// CompileCondition is like Compile, but also makes sure the expression
+// evaluates to a boolean value.
+func CompileCondition(source string) (*Expression, error) {
+	expression, err := Compile(source)
+	if err != nil {
+		return nil, err
+	}
+
+	kind := expression.root.kind()
+	if kind != kindUnknown && kind != kindNull && kind != kindBool {
+		return nil, withSource(newError(expression.root.position(),
+			"expected a boolean result, got %s", kind), source)
+	}
+
+	return expression, nil
+}
+
+// String returns the source of the expression.
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Evaluate evaluates the expression against the given item. The returned
+// value is nil, a bool, a float64, a string, a time.Time, a time.Duration or a
+// []interface{}.
+func (e *Expression) Evaluate(item *datatypes.PipelineItem) (interface{}, error) {
+	value, err := e.root.evaluate(&environment{item, time.Now()})
+	if err != nil {
+		return nil, withSource(err, e.source)
+	}
+
+	return value, nil
+}
+
+// Match evaluates the expression against the given item and returns its
+// boolean result. Null results are considered false.
+func (e *Expression) Match(item *datatypes.PipelineItem) (bool, error) {
+	value, err := e.Evaluate(item)
+	if err != nil {
+		return false, err
+	}
+
+	switch typedValue := value.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return typedValue, nil
+	}
+
+	return false, withSource(newError(e.root.position(), "expected a "+
+		"boolean result, got %s", kindOf(value)), e.source)
+}
+
+func withSource(err error, source string) error {
+	expressionError, ok := err.(*Error)
+	if ok {
+		expressionError.Expression = source
+	}
+
+	return err
+}
@@ -0,0 +1,380 @@
+package expr
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+)
+
+type testPayload struct {
+	Title string
+	Count int
+}
+
+func newTestItem() *datatypes.PipelineItem {
+	item := datatypes.NewPipelineItem("test")
+	item.SetName("Some.Linux.Distro.iso")
+	item.SetDescription("A Linux distribution")
+	item.SetDate(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+	item.AddUrlString("http://example.com/distro.iso")
+	item.AddUrlString("http://mirror.example.org/distro.iso")
+	item.SetSize(700 << 20)
+	item.AddTags("linux", "iso")
+	item.SetMetadata("flag", true)
+	item.SetMetadata("map", map[string]interface{}{"key": "value"})
+	item.AddPayload("struct", testPayload{"title", 3})
+
+	return item
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{`1 + 2`, 3.0},
+		{`-1 - 2`, -3.0},
+		{`"a" + "b"`, "ab"},
+		{`1h + 30m`, 90 * time.Minute},
+		{`7d`, 7 * 24 * time.Hour},
+		{`2w - 1d`, 13 * 24 * time.Hour},
+		{`700MB`, 700.0 * 1000 * 1000},
+		{`4GiB`, 4.0 * 1024 * 1024 * 1024},
+		{`1 < 2 && 2 <= 2 && 3 > 2 && 3 >= 3`, true},
+		{`1 == 2 || !(1 != 2)`, false},
+		{`true and not false or false`, true},
+		{`name`, "Some.Linux.Distro.iso"},
+		{`lower(name)`, "some.linux.distro.iso"},
+		{`upper("a")`, "A"},
+		{`trim("  a  ")`, "a"},
+		{`len(name)`, 21.0},
+		{`len(urls)`, 2.0},
+		{`host(url)`, "example.com"},
+		{`host(metadata.missing)`, nil},
+		{`url`, "http://example.com/distro.iso"},
+		{`size`, float64(700 << 20)},
+		{`size < 1GiB`, true},
+		{`tags`, []interface{}{"linux", "iso"}},
+		{`"linux" in tags`, true},
+		{`tags contains "iso"`, true},
+		{`"bsd" in tags`, false},
+		{`"Linux" in description`, true},
+		{`name matches "(?i)linux"`, true},
+		{`name matches "^linux"`, false},
+		{`name startswith "Some"`, true},
+		{`name endswith ".iso"`, true},
+		{`2 in [1, 2, 3]`, true},
+		{`[1, "a"]`, []interface{}{1.0, "a"}},
+		{`date > "2020-01-01"`, true},
+		{`date < "2020-01-02 03:04:05"`, false},
+		{`date == "2020-01-02T03:04:05Z"`, true},
+		{`date + 1d > "2020-01-03"`, true},
+		{`date - date`, time.Duration(0)},
+		{`metadata.flag`, true},
+		{`metadata.map.key`, "value"},
+		{`payload.struct.Title`, "title"},
+		{`payload.struct.Count + 1`, 4.0},
+		{`metadata.missing`, nil},
+		{`metadata.missing == null`, true},
+		{`metadata.missing != null`, false},
+		{`metadata.missing < 1`, false},
+		{`metadata.missing + 1`, nil},
+		{`metadata.missing matches "a"`, false},
+		{"`a\\.b`", `a\.b`},
+		{`"a\"b"`, `a"b`},
+	}
+
+	item := newTestItem()
+
+	for _, test := range tests {
+		expression, err := Compile(test.expression)
+		if err != nil {
+			t.Errorf("Compile(%q) : %v", test.expression, err)
+			continue
+		}
+
+		value, err := expression.Evaluate(item)
+		if err != nil {
+			t.Errorf("Evaluate(%q) : %v", test.expression, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(value, test.expected) {
+			t.Errorf("Evaluate(%q) = %#v, expected %#v", test.expression,
+				value, test.expected)
+		}
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		message    string
+	}{
+		{`payload.struct == "x"`, "unsupported value of type"},
+		{`metadata.map == 1`, "unsupported value of type"},
+		{`metadata.flag == payload.struct`, "unsupported value of type"},
+		{`payload.struct matches "x"`, "unsupported value of type"},
+		{`payload.struct startswith "a"`, "unsupported value of type"},
+		{`1 in payload.struct`, "unsupported value of type"},
+		{`payload.struct + 1 > 2`, "unsupported value of type"},
+		{`metadata.flag < true`, "operator < can't be used with booleans"},
+		{`metadata.flag + 1`, "operator + can't be used with boolean and number"},
+		{`metadata.flag == 1`, "can't compare boolean with number"},
+		{`name matches metadata.map.key + "("`, "invalid regular expression"},
+		{`date > metadata.map.key`, `can't parse "value" as a time`},
+		{`len(metadata.flag)`, "len can't be used with boolean"},
+		{`!metadata.map.key`, ""},
+	}
+
+	item := newTestItem()
+
+	for _, test := range tests {
+		expression, err := Compile(test.expression)
+		if err != nil {
+			t.Errorf("Compile(%q) : %v", test.expression, err)
+			continue
+		}
+
+		_, err = expression.Evaluate(item)
+		if err == nil {
+			t.Errorf("Evaluate(%q) : expected an error", test.expression)
+			continue
+		}
+
+		if _, ok := err.(*Error); !ok {
+			t.Errorf("Evaluate(%q) : expected an *Error, got %T",
+				test.expression, err)
+		}
+
+		if !strings.Contains(err.Error(), test.message) {
+			t.Errorf("Evaluate(%q) : error %q does not contain %q",
+				test.expression, err, test.message)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		column     int
+	}{
+		{``, 1},
+		{`name ==`, 8},
+		{`(name`, 6},
+		{`name == "a`, 9},
+		{`unknown`, 1},
+		{`metadata.`, 1},
+		{`1 + "a"`, 3},
+		{`1 < "a"`, 3},
+		{`true < false`, 6},
+		{`"a" - "b"`, 5},
+		{`name matches "("`, 14},
+		{`nosuchfunction(name)`, 1},
+		{`lower(name, name)`, 1},
+		{`1 && true`, 1},
+		{`name 1`, 6},
+		{`3e9`, 1},
+	}
+
+	for _, test := range tests {
+		_, err := Compile(test.expression)
+		if err == nil {
+			t.Errorf("Compile(%q) : expected an error", test.expression)
+			continue
+		}
+
+		exprErr, ok := err.(*Error)
+		if !ok {
+			t.Errorf("Compile(%q) : expected an *Error, got %T",
+				test.expression, err)
+			continue
+		}
+
+		if exprErr.Column != test.column {
+			t.Errorf("Compile(%q) : error %q at column %d, expected "+
+				"column %d", test.expression, err, exprErr.Column,
+				test.column)
+		}
+	}
+}
+
+func TestCompileCondition(t *testing.T) {
+	for _, expression := range []string{`name`, `1 + 1`, `[true]`} {
+		_, err := CompileCondition(expression)
+		if err == nil {
+			t.Errorf("CompileCondition(%q) : expected an error", expression)
+		}
+	}
+
+	for _, expression := range []string{`true`, `metadata.flag`, `null`} {
+		_, err := CompileCondition(expression)
+		if err != nil {
+			t.Errorf("CompileCondition(%q) : %v", expression, err)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	item := newTestItem()
+
+	tests := []struct {
+		expression string
+		expected   bool
+		err        bool
+	}{
+		{`size > 100MB`, true, false},
+		{`metadata.missing`, false, false},
+		{`metadata.map.key`, false, true},
+	}
+
+	for _, test := range tests {
+		expression, err := Compile(test.expression)
+		if err != nil {
+			t.Fatalf("Compile(%q) : %v", test.expression, err)
+		}
+
+		matched, err := expression.Match(item)
+		if (err != nil) != test.err {
+			t.Errorf("Match(%q) : unexpected error %v", test.expression, err)
+			continue
+		}
+
+		if matched != test.expected {
+			t.Errorf("Match(%q) = %v, expected %v", test.expression,
+				matched, test.expected)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected time.Duration
+		err      bool
+	}{
+		{"30m", 30 * time.Minute, false},
+		{"1h30m", 90 * time.Minute, false},
+		{"2d", 48 * time.Hour, false},
+		{"1w2d", 9 * 24 * time.Hour, false},
+		{"1.5h", 90 * time.Minute, false},
+		{"", 0, true},
+		{"1x", 0, true},
+		{"h", 0, true},
+	}
+
+	for _, test := range tests {
+		duration, err := ParseDuration(test.text)
+		if (err != nil) != test.err {
+			t.Errorf("ParseDuration(%q) : unexpected error %v", test.text,
+				err)
+			continue
+		}
+
+		if duration != test.expected {
+			t.Errorf("ParseDuration(%q) = %v, expected %v", test.text,
+				duration, test.expected)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected int64
+		err      bool
+	}{
+		{"100", 100, false},
+		{"700MB", 700 * 1000 * 1000, false},
+		{"700mb", 700 * 1000 * 1000, false},
+		{"4GiB", 4 << 30, false},
+		{"1.5KB", 1500, false},
+		{"1KiB", 1024, false},
+		{"10XB", 0, true},
+		{"MB", 0, true},
+		{"", 0, true},
+	}
+
+	for _, test := range tests {
+		size, err := ParseSize(test.text)
+		if (err != nil) != test.err {
+			t.Errorf("ParseSize(%q) : unexpected error %v", test.text, err)
+			continue
+		}
+
+		if size != test.expected {
+			t.Errorf("ParseSize(%q) = %d, expected %d", test.text, size,
+				test.expected)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected time.Time
+		err      bool
+	}{
+		{"2020-01-02", time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), false},
+		{"2020-01-02 03:04", time.Date(2020, 1, 2, 3, 4, 0, 0, time.UTC),
+			false},
+		{"2020-01-02T03:04:05Z", time.Date(2020, 1, 2, 3, 4, 5, 0,
+			time.UTC), false},
+		{"yesterday", time.Time{}, true},
+	}
+
+	for _, test := range tests {
+		parsedTime, err := ParseTime(test.text)
+		if (err != nil) != test.err {
+			t.Errorf("ParseTime(%q) : unexpected error %v", test.text, err)
+			continue
+		}
+
+		if !parsedTime.Equal(test.expected) {
+			t.Errorf("ParseTime(%q) = %v, expected %v", test.text,
+				parsedTime, test.expected)
+		}
+	}
+}
+
+func TestCompileField(t *testing.T) {
+	item := newTestItem()
+
+	tests := []struct {
+		name     string
+		expected interface{}
+	}{
+		{"name", "Some.Linux.Distro.iso"},
+		{"size", float64(700 << 20)},
+		{"metadata.size", float64(700 << 20)},
+		{"urls", []interface{}{"http://example.com/distro.iso",
+			"http://mirror.example.org/distro.iso"}},
+		{"metadata.map.key", "value"},
+		{"payload.struct.Title", "title"},
+		{"metadata.missing", nil},
+	}
+
+	for _, test := range tests {
+		field, err := CompileField(test.name)
+		if err != nil {
+			t.Errorf("CompileField(%q) : %v", test.name, err)
+			continue
+		}
+
+		value := field.Value(item)
+		if !reflect.DeepEqual(value, test.expected) {
+			t.Errorf("CompileField(%q).Value = %#v, expected %#v",
+				test.name, value, test.expected)
+		}
+	}
+
+	for _, name := range []string{"", "unknown", "metadata.", "payload"} {
+		_, err := CompileField(name)
+		if err == nil {
+			t.Errorf("CompileField(%q) : expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package expr
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+)
+
+// itemFields are the item fields that can be used in expressions.
+var itemFields = map[string]struct {
+	kind valueKind
+	get  func(item *datatypes.PipelineItem) interface{}
+}{
+	"id": {kindString, func(item *datatypes.PipelineItem) interface{} {
+		return item.GetId()
+	}},
+	"fingerprint": {kindString, func(item *datatypes.PipelineItem) interface{} {
+		return item.GetFingerprint()
+	}},
+	"input": {kindString, func(item *datatypes.PipelineItem) interface{} {
+		return item.GetInputGenericId()
+	}},
+	"name": {kindString, func(item *datatypes.PipelineItem) interface{} {
+		return item.GetName()
+	}},
+	"description": {kindString, func(item *datatypes.PipelineItem) interface{} {
+		return item.GetDescription()
+	}},
+	"date": {kindTime, func(item *datatypes.PipelineItem) interface{} {
+		return item.GetDate()
+	}},
+	"url": {kindString, func(item *datatypes.PipelineItem) interface{} {
+		itemUrl, err := item.GetUrl(0)
+		if err != nil {
+			return nil
+		}
+		return itemUrl.String()
+	}},
+	"urls": {kindList, func(item *datatypes.PipelineItem) interface{} {
+		return item.GetUrls()
+	}},
+}
+
+// metadataFields are the well-known metadata fields, which can be used in
+// expressions without the "metadata." prefix.
+var metadataFields = map[string]valueKind{
+	datatypes.MetadataSize:        kindNumber,
+	datatypes.MetadataContentType: kindString,
+	datatypes.MetadataAuthor:      kindString,
+	datatypes.MetadataTags:        kindList,
+	datatypes.MetadataCategories:  kindList,
+	datatypes.MetadataGuid:        kindString,
+}
+
+// resolveField returns the node for the given field name or a non-nil error
+// if the field is not known.
+func resolveField(name string, pos int) (node, error) {
+	field, ok := itemFields[name]
+	if ok {
+		return &fieldNode{pos, name, field.kind, field.get}, nil
+	}
+
+	kind, ok := metadataFields[name]
+	if ok {
+		return &fieldNode{pos, name, kind, metadataGetter(name, nil)}, nil
+	}
+
+	path := strings.Split(name, ".")
+	for _, element := range path {
+		if element == "" {
+			return nil, newError(pos, "invalid field name %q", name)
+		}
+	}
+
+	switch {
+	case path[0] == "metadata" && len(path) > 1:
+		return &fieldNode{pos, name, kindUnknown,
+			metadataGetter(path[1], path[2:])}, nil
+	case path[0] == "payload" && len(path) > 1:
+		return &fieldNode{pos, name, kindUnknown,
+			payloadGetter(path[1], path[2:])}, nil
+	}
+
+	return nil, newError(pos, "unknown field %q (valid fields are %s, "+
+		"metadata.<key> and payload.<id>[.<field>...])", name,
+		strings.Join(knownFields(), ", "))
+}
+
+// Field is an item field, named as in expressions (for example, "name",
+// "size", "metadata.series" or "payload.fetch.StatusCode"). It lets modules
+// that work on a single field use the same field names as expressions.
+type Field struct {
+	name string
+	get  func(*datatypes.PipelineItem) interface{}
+}
+
+// CompileField returns the field with the given name or a non-nil *Error if
+// the field is not known.
+func CompileField(name string) (*Field, error) {
+	resolved, err := resolveField(name, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Field{name, resolved.(*fieldNode).get}, nil
+}
+
+// Value returns the value of the field for the given item, normalized as in
+// expressions, or nil if the item does not have it.
+func (f *Field) Value(item *datatypes.PipelineItem) interface{} {
+	return normalize(f.get(item))
+}
+
+// String returns the field name. This satisfies the fmt.Stringer interface.
+func (f *Field) String() string {
+	return f.name
+}
+
+func metadataGetter(key string,
+	path []string) func(*datatypes.PipelineItem) interface{} {
+	return func(item *datatypes.PipelineItem) interface{} {
+		value, ok := item.GetMetadata(key)
+		if !ok {
+			return nil
+		}
+
+		value, err := Navigate(value, path)
+		if err != nil {
+			return nil
+		}
+
+		return value
+	}
+}
+
+func payloadGetter(payloadId string,
+	path []string) func(*datatypes.PipelineItem) interface{} {
+	return func(item *datatypes.PipelineItem) interface{} {
+		payload, err := item.GetPayload(payloadId)
+		if err != nil {
+			return nil
+		}
+
+		value, err := Navigate(payload, path)
+		if err != nil {
+			return nil
+		}
+
+		return value
+	}
+}
+
+func knownFields() []string {
+	fields := make([]string, 0, len(itemFields)+len(metadataFields))
+	for field := range itemFields {
+		fields = append(fields, field)
+	}
+	for field := range metadataFields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	return fields
+}
+
+// Navigate follows the given path of struct field names (case-insensitive) or
+// map keys starting at value and returns the value found at the end of it.
+func Navigate(value interface{}, path []string) (interface{}, error) {
+	current := reflect.ValueOf(value)
+	for _, element := range path {
+		for current.Kind() == reflect.Ptr ||
+			current.Kind() == reflect.Interface {
+			if current.IsNil() {
+				return nil, fmt.Errorf("nil value at %q", element)
+			}
+			current = current.Elem()
+		}
+
+		switch current.Kind() {
+		case reflect.Struct:
+			fieldValue := current.FieldByNameFunc(func(name string) bool {
+				return strings.EqualFold(name, element)
+			})
+			if !fieldValue.IsValid() {
+				return nil, fmt.Errorf("no field %q", element)
+			}
+			if !fieldValue.CanInterface() {
+				return nil, fmt.Errorf("field %q is not exported",
+					element)
+			}
+			current = fieldValue
+		case reflect.Map:
+			if current.Type().Key().Kind() != reflect.String {
+				return nil, fmt.Errorf("map at %q does not have "+
+					"string keys", element)
+			}
+			mapValue := current.MapIndex(reflect.ValueOf(element).Convert(
+				current.Type().Key()))
+			if !mapValue.IsValid() {
+				return nil, fmt.Errorf("no key %q", element)
+			}
+			current = mapValue
+		default:
+			return nil, fmt.Errorf("can't get %q from %s", element,
+				current.Kind())
+		}
+	}
+
+	if !current.IsValid() {
+		return nil, nil
+	}
+
+	return current.Interface(), nil
+}
@@ -0,0 +1,252 @@
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdentifier
+	tokenNumber
+	tokenDuration
+	tokenString
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	pos   int
+	value interface{}
+}
+
+// operators are sorted so longer operators are matched first.
+var operators = []string{
+	"&&", "||", "==", "!=", "<=", ">=",
+	"<", ">", "!", "+", "-", "(", ")", "[", "]", ",",
+}
+
+// sizeUnits are the supported size suffixes for numbers. Units without an "i"
+// are decimal (powers of 1000) and units with an "i" are binary (powers of
+// 1024). Units are case-insensitive.
+var sizeUnits = map[string]float64{
+	"b":   1,
+	"kb":  1e3,
+	"mb":  1e6,
+	"gb":  1e9,
+	"tb":  1e12,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+}
+
+// durationUnits are the supported duration suffixes for numbers.
+var durationUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"µs": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+	"w":  7 * 24 * time.Hour,
+}
+
+var durationSegmentRegexp = regexp.MustCompile(`([0-9]+(?:\.[0-9]+)?)([a-zµ]+)`)
+
+// tokenize splits the given source into tokens. Token positions are 1-based
+// columns.
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+
+	offset := 0
+	for offset < len(source) {
+		r, size := utf8.DecodeRuneInString(source[offset:])
+		pos := utf8.RuneCountInString(source[:offset]) + 1
+
+		switch {
+		case unicode.IsSpace(r):
+			offset += size
+		case r == '"' || r == '`':
+			text, err := scanString(source[offset:])
+			if err != nil {
+				return nil, &Error{Column: pos, Message: err.Error()}
+			}
+			value, err := strconv.Unquote(text)
+			if err != nil {
+				return nil, &Error{Column: pos,
+					Message: fmt.Sprintf("invalid string %s (backslashes "+
+						"must be escaped in double quoted strings)", text)}
+			}
+			tokens = append(tokens, token{tokenString, text, pos, value})
+			offset += len(text)
+		case unicode.IsDigit(r):
+			text := scanWhile(source[offset:], func(r rune) bool {
+				return unicode.IsLetter(r) || unicode.IsDigit(r) ||
+					r == '.'
+			})
+			t, err := parseNumber(text, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, t)
+			offset += len(text)
+		case unicode.IsLetter(r) || r == '_':
+			text := scanWhile(source[offset:], func(r rune) bool {
+				return unicode.IsLetter(r) || unicode.IsDigit(r) ||
+					r == '_' || r == '.'
+			})
+			tokens = append(tokens, token{tokenIdentifier, text, pos, nil})
+			offset += len(text)
+		default:
+			operator := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(source[offset:], candidate) {
+					operator = candidate
+					break
+				}
+			}
+			if operator == "" {
+				return nil, &Error{Column: pos,
+					Message: fmt.Sprintf("unexpected character %q", r)}
+			}
+			tokens = append(tokens, token{tokenOperator, operator, pos, nil})
+			offset += len(operator)
+		}
+	}
+
+	tokens = append(tokens, token{tokenEOF, "",
+		utf8.RuneCountInString(source) + 1, nil})
+
+	return tokens, nil
+}
+
+// scanString returns the quoted string (including quotes) at the start of the
+// given text.
+func scanString(text string) (string, error) {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			if quote == '"' {
+				i++
+			}
+		case quote:
+			return text[:i+1], nil
+		}
+	}
+
+	return "", fmt.Errorf("unterminated string")
+}
+
+func scanWhile(text string, accept func(rune) bool) string {
+	for i, r := range text {
+		if !accept(r) {
+			return text[:i]
+		}
+	}
+
+	return text
+}
+
+// parseNumber parses a number token, which can be a plain number (42, 1.5), a
+// size (4GB, 700MiB) or a duration (24h, 1h30m, 7d).
+func parseNumber(text string, pos int) (token, error) {
+	number, ok := parseSize(text)
+	if ok {
+		return token{tokenNumber, text, pos, number}, nil
+	}
+
+	duration, err := ParseDuration(text)
+	if err == nil {
+		return token{tokenDuration, text, pos, duration}, nil
+	}
+
+	return token{}, &Error{Column: pos,
+		Message: fmt.Sprintf("invalid number, size or duration %q", text)}
+}
+
+// parseSize parses a number with an optional size unit (see sizeUnits). It
+// returns false if the given text is not a valid size.
+func parseSize(text string) (float64, bool) {
+	numberText := scanWhile(text, func(r rune) bool {
+		return unicode.IsDigit(r) || r == '.'
+	})
+
+	number, err := strconv.ParseFloat(numberText, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	suffix := strings.TrimSpace(text[len(numberText):])
+	if suffix == "" {
+		return number, true
+	}
+
+	unit, ok := sizeUnits[strings.ToLower(suffix)]
+	if !ok {
+		return 0, false
+	}
+
+	return number * unit, true
+}
+
+// ParseSize parses sizes like "700MB", "4GiB" or "1024" (bytes). Units without
+// an "i" are decimal (powers of 1000) and units with an "i" are binary (powers
+// of 1024). Units are case-insensitive.
+func ParseSize(text string) (int64, error) {
+	number, ok := parseSize(text)
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q", text)
+	}
+
+	return int64(number), nil
+}
+
+// ParseDuration parses durations like time.ParseDuration, but also supports
+// days ("d") and weeks ("w"). For example, "1d12h" or "2w".
+func ParseDuration(text string) (time.Duration, error) {
+	invalid := fmt.Errorf("invalid duration %q", text)
+
+	matches := durationSegmentRegexp.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return 0, invalid
+	}
+
+	var duration time.Duration
+	end := 0
+	for _, match := range matches {
+		if match[0] != end {
+			return 0, invalid
+		}
+		end = match[1]
+
+		number, err := strconv.ParseFloat(text[match[2]:match[3]], 64)
+		if err != nil {
+			return 0, invalid
+		}
+
+		unit, ok := durationUnits[text[match[4]:match[5]]]
+		if !ok {
+			return 0, invalid
+		}
+
+		duration += time.Duration(number * float64(unit))
+	}
+
+	if end != len(text) {
+		return 0, invalid
+	}
+
+	return duration, nil
+}
@@ -0,0 +1,336 @@
+package expr
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+)
+
+// environment holds the data available while evaluating an expression.
+type environment struct {
+	item *datatypes.PipelineItem
+	now  time.Time
+}
+
+// node is a node of a compiled expression.
+type node interface {
+	// position returns the column the node starts at.
+	position() int
+
+	// kind returns the kind of the values the node evaluates to, if known
+	// at compile time, or kindUnknown otherwise.
+	kind() valueKind
+
+	evaluate(env *environment) (interface{}, error)
+}
+
+type literalNode struct {
+	pos   int
+	value interface{}
+}
+
+func (n *literalNode) position() int   { return n.pos }
+func (n *literalNode) kind() valueKind { return kindOf(n.value) }
+
+func (n *literalNode) evaluate(env *environment) (interface{}, error) {
+	return n.value, nil
+}
+
+type nowNode struct {
+	pos int
+}
+
+func (n *nowNode) position() int   { return n.pos }
+func (n *nowNode) kind() valueKind { return kindTime }
+
+func (n *nowNode) evaluate(env *environment) (interface{}, error) {
+	return env.now, nil
+}
+
+type fieldNode struct {
+	pos       int
+	name      string
+	fieldKind valueKind
+	get       func(item *datatypes.PipelineItem) interface{}
+}
+
+func (n *fieldNode) position() int   { return n.pos }
+func (n *fieldNode) kind() valueKind { return n.fieldKind }
+
+func (n *fieldNode) evaluate(env *environment) (interface{}, error) {
+	return normalize(n.get(env.item)), nil
+}
+
+type listNode struct {
+	pos      int
+	elements []node
+}
+
+func (n *listNode) position() int   { return n.pos }
+func (n *listNode) kind() valueKind { return kindList }
+
+func (n *listNode) evaluate(env *environment) (interface{}, error) {
+	list := make([]interface{}, len(n.elements))
+	for i, element := range n.elements {
+		value, err := element.evaluate(env)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = value
+	}
+
+	return list, nil
+}
+
+type notNode struct {
+	pos     int
+	operand node
+}
+
+func (n *notNode) position() int   { return n.pos }
+func (n *notNode) kind() valueKind { return kindBool }
+
+func (n *notNode) evaluate(env *environment) (interface{}, error) {
+	value, err := evaluateBool(n.operand, env, "!")
+	if err != nil {
+		return nil, err
+	}
+
+	return !value, nil
+}
+
+type negateNode struct {
+	pos     int
+	operand node
+}
+
+func (n *negateNode) position() int   { return n.pos }
+func (n *negateNode) kind() valueKind { return n.operand.kind() }
+
+func (n *negateNode) evaluate(env *environment) (interface{}, error) {
+	value, err := n.operand.evaluate(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch typedValue := value.(type) {
+	case nil:
+		return nil, nil
+	case float64:
+		return -typedValue, nil
+	case time.Duration:
+		return -typedValue, nil
+	}
+
+	return nil, newError(n.pos, "operator - can't be used with %s",
+		kindOf(value))
+}
+
+type logicalNode struct {
+	pos         int
+	operator    string
+	left, right node
+}
+
+func (n *logicalNode) position() int   { return n.pos }
+func (n *logicalNode) kind() valueKind { return kindBool }
+
+func (n *logicalNode) evaluate(env *environment) (interface{}, error) {
+	left, err := evaluateBool(n.left, env, n.operator)
+	if err != nil {
+		return nil, err
+	}
+
+	// Short-circuit evaluation.
+	if (n.operator == "&&") == !left {
+		return left, nil
+	}
+
+	return evaluateBool(n.right, env, n.operator)
+}
+
+type comparisonNode struct {
+	pos         int
+	operator    string
+	left, right node
+}
+
+func (n *comparisonNode) position() int   { return n.pos }
+func (n *comparisonNode) kind() valueKind { return kindBool }
+
+func (n *comparisonNode) evaluate(env *environment) (interface{}, error) {
+	left, right, err := evaluateBoth(n.left, n.right, env)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := compare(n.operator, left, right)
+	if err != nil {
+		return nil, newError(n.pos, "%v", err)
+	}
+
+	return result, nil
+}
+
+type arithmeticNode struct {
+	pos         int
+	operator    string
+	left, right node
+	resultKind  valueKind
+}
+
+func (n *arithmeticNode) position() int   { return n.pos }
+func (n *arithmeticNode) kind() valueKind { return n.resultKind }
+
+func (n *arithmeticNode) evaluate(env *environment) (interface{}, error) {
+	left, right, err := evaluateBoth(n.left, n.right, env)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := arithmetic(n.operator, left, right)
+	if err != nil {
+		return nil, newError(n.pos, "%v", err)
+	}
+
+	return result, nil
+}
+
+type matchNode struct {
+	pos         int
+	operator    string
+	left, right node
+
+	// pattern is the precompiled regular expression for the matches
+	// operator when the right side is a literal.
+	pattern *regexp.Regexp
+}
+
+func (n *matchNode) position() int   { return n.pos }
+func (n *matchNode) kind() valueKind { return kindBool }
+
+func (n *matchNode) evaluate(env *environment) (interface{}, error) {
+	left, right, err := evaluateBoth(n.left, n.right, env)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := match(n.operator, left, right, n.pattern)
+	if err != nil {
+		return nil, newError(n.pos, "%v", err)
+	}
+
+	return result, nil
+}
+
+// function is a built-in function that can be called from expressions.
+type function struct {
+	arguments  int
+	resultKind valueKind
+	call       func(arguments []interface{}) (interface{}, error)
+}
+
+var functions = map[string]*function{
+	"lower": {1, kindString, stringFunction(strings.ToLower)},
+	"upper": {1, kindString, stringFunction(strings.ToUpper)},
+	"trim":  {1, kindString, stringFunction(strings.TrimSpace)},
+	"host": {1, kindString, func(arguments []interface{}) (interface{}, error) {
+		if arguments[0] == nil {
+			return nil, nil
+		}
+		parsedUrl, err := url.Parse(ToString(arguments[0]))
+		if err != nil {
+			return nil, err
+		}
+		return parsedUrl.Hostname(), nil
+	}},
+	"len": {1, kindNumber, func(arguments []interface{}) (interface{}, error) {
+		switch typedValue := arguments[0].(type) {
+		case nil:
+			return nil, nil
+		case string:
+			return float64(utf8.RuneCountInString(typedValue)), nil
+		case []interface{}:
+			return float64(len(typedValue)), nil
+		}
+		return nil, fmt.Errorf("len can't be used with %s",
+			kindOf(arguments[0]))
+	}},
+}
+
+func stringFunction(
+	f func(string) string) func([]interface{}) (interface{}, error) {
+	return func(arguments []interface{}) (interface{}, error) {
+		if arguments[0] == nil {
+			return nil, nil
+		}
+		return f(ToString(arguments[0])), nil
+	}
+}
+
+type callNode struct {
+	pos       int
+	name      string
+	function  *function
+	arguments []node
+}
+
+func (n *callNode) position() int   { return n.pos }
+func (n *callNode) kind() valueKind { return n.function.resultKind }
+
+func (n *callNode) evaluate(env *environment) (interface{}, error) {
+	arguments := make([]interface{}, len(n.arguments))
+	for i, argument := range n.arguments {
+		value, err := argument.evaluate(env)
+		if err != nil {
+			return nil, err
+		}
+		arguments[i] = value
+	}
+
+	result, err := n.function.call(arguments)
+	if err != nil {
+		return nil, newError(n.pos, "%v", err)
+	}
+
+	return result, nil
+}
+
+func evaluateBoth(left, right node,
+	env *environment) (interface{}, interface{}, error) {
+	leftValue, err := left.evaluate(env)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rightValue, err := right.evaluate(env)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return leftValue, rightValue, nil
+}
+
+// evaluateBool evaluates the given node, which must result in a boolean value.
+// Null values are considered false.
+func evaluateBool(n node, env *environment, operator string) (bool, error) {
+	value, err := n.evaluate(env)
+	if err != nil {
+		return false, err
+	}
+
+	switch typedValue := value.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return typedValue, nil
+	}
+
+	return false, newError(n.position(), "operator %s expects a boolean, "+
+		"got %s", operator, kindOf(value))
+}
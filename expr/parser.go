@@ -0,0 +1,361 @@
+package expr
+
+import (
+	"regexp"
+)
+
+// parser is a recursive descent parser for expressions. The grammar, from
+// lowest to highest precedence, is:
+//
+//	or         = and { ( "||" | "or" ) and }
+//	and        = not { ( "&&" | "and" ) not }
+//	not        = ( "!" | "not" ) not | comparison
+//	comparison = additive [ operator additive ]
+//	additive   = unary { ( "+" | "-" ) unary }
+//	unary      = "-" unary | primary
+//	primary    = literal | field | function "(" [ or { "," or } ] ")" |
+//	             "(" or ")" | "[" [ or { "," or } ] "]"
+type parser struct {
+	tokens  []token
+	current int
+}
+
+var comparisonOperators = map[string]bool{
+	"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true,
+}
+
+var matchOperators = map[string]bool{
+	"matches": true, "contains": true, "startswith": true, "endswith": true,
+	"in": true,
+}
+
+func parse(source string) (node, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens, 0}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek().kind != tokenEOF {
+		return nil, p.unexpected()
+	}
+
+	return root, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.current]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.current]
+	if t.kind != tokenEOF {
+		p.current++
+	}
+
+	return t
+}
+
+// accept consumes the next token and returns true if it is an operator or an
+// identifier with one of the given texts.
+func (p *parser) accept(texts ...string) (token, bool) {
+	t := p.peek()
+	if t.kind != tokenOperator && t.kind != tokenIdentifier {
+		return t, false
+	}
+
+	for _, text := range texts {
+		if t.text == text {
+			return p.next(), true
+		}
+	}
+
+	return t, false
+}
+
+func (p *parser) expect(text string) error {
+	_, ok := p.accept(text)
+	if !ok {
+		t := p.peek()
+		if t.kind == tokenEOF {
+			return newError(t.pos, "expected %q, got end of expression",
+				text)
+		}
+		return newError(t.pos, "expected %q, got %q", text, t.text)
+	}
+
+	return nil
+}
+
+func (p *parser) unexpected() error {
+	t := p.peek()
+	if t.kind == tokenEOF {
+		return newError(t.pos, "unexpected end of expression")
+	}
+
+	return newError(t.pos, "unexpected %q", t.text)
+}
+
+func (p *parser) parseOr() (node, error) {
+	return p.parseLogical(p.parseAnd, "||", "or")
+}
+
+func (p *parser) parseAnd() (node, error) {
+	return p.parseLogical(p.parseNot, "&&", "and")
+}
+
+func (p *parser) parseLogical(parseOperand func() (node, error),
+	operator, keyword string) (node, error) {
+	left, err := parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t, ok := p.accept(operator, keyword)
+		if !ok {
+			return left, nil
+		}
+
+		right, err := parseOperand()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, operand := range []node{left, right} {
+			err = checkBool(operand, operator)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		left = &logicalNode{t.pos, operator, left, right}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	t, ok := p.accept("!", "not")
+	if !ok {
+		return p.parseComparison()
+	}
+
+	operand, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkBool(operand, "!")
+	if err != nil {
+		return nil, err
+	}
+
+	return &notNode{t.pos, operand}, nil
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	if (t.kind != tokenOperator || !comparisonOperators[t.text]) &&
+		(t.kind != tokenIdentifier || !matchOperators[t.text]) {
+		return left, nil
+	}
+	p.next()
+
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	if comparisonOperators[t.text] {
+		err = checkComparison(t.text, left.kind(), right.kind())
+		if err != nil {
+			return nil, newError(t.pos, "%v", err)
+		}
+
+		return &comparisonNode{t.pos, t.text, left, right}, nil
+	}
+
+	err = checkMatch(t.text, left.kind(), right.kind())
+	if err != nil {
+		return nil, newError(t.pos, "%v", err)
+	}
+
+	var pattern *regexp.Regexp
+	if literal, ok := right.(*literalNode); ok && t.text == "matches" {
+		pattern, err = regexp.Compile(literal.value.(string))
+		if err != nil {
+			return nil, newError(right.position(),
+				"invalid regular expression : %v", err)
+		}
+	}
+
+	return &matchNode{t.pos, t.text, left, right, pattern}, nil
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		kind, err := arithmeticKind(t.text, left.kind(), right.kind())
+		if err != nil {
+			return nil, newError(t.pos, "%v", err)
+		}
+
+		left = &arithmeticNode{t.pos, t.text, left, right, kind}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	t, ok := p.accept("-")
+	if !ok {
+		return p.parsePrimary()
+	}
+
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	kind := operand.kind()
+	if kind != kindUnknown && kind != kindNull && kind != kindNumber &&
+		kind != kindDuration {
+		return nil, newError(t.pos, "operator - can't be used with %s", kind)
+	}
+
+	return &negateNode{t.pos, operand}, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.peek()
+
+	switch t.kind {
+	case tokenNumber, tokenDuration, tokenString:
+		p.next()
+		return &literalNode{t.pos, t.value}, nil
+	case tokenIdentifier:
+		p.next()
+		switch t.text {
+		case "true", "false":
+			return &literalNode{t.pos, t.text == "true"}, nil
+		case "null":
+			return &literalNode{t.pos, nil}, nil
+		case "now":
+			return &nowNode{t.pos}, nil
+		}
+
+		if _, ok := p.accept("("); ok {
+			return p.parseCall(t)
+		}
+
+		if matchOperators[t.text] || t.text == "and" || t.text == "or" ||
+			t.text == "not" {
+			p.current--
+			return nil, p.unexpected()
+		}
+
+		return resolveField(t.text, t.pos)
+	case tokenOperator:
+		switch t.text {
+		case "(":
+			p.next()
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			err = p.expect(")")
+			if err != nil {
+				return nil, err
+			}
+			return inner, nil
+		case "[":
+			p.next()
+			elements, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+			return &listNode{t.pos, elements}, nil
+		}
+	}
+
+	return nil, p.unexpected()
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	f, ok := functions[name.text]
+	if !ok {
+		return nil, newError(name.pos, "unknown function %q", name.text)
+	}
+
+	arguments, err := p.parseList(")")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(arguments) != f.arguments {
+		return nil, newError(name.pos, "function %s expects %d "+
+			"argument(s), got %d", name.text, f.arguments, len(arguments))
+	}
+
+	return &callNode{name.pos, name.text, f, arguments}, nil
+}
+
+// parseList parses a comma separated list of expressions up to (and
+// including) the given closing token.
+func (p *parser) parseList(closing string) ([]node, error) {
+	var elements []node
+	if _, ok := p.accept(closing); ok {
+		return elements, nil
+	}
+
+	for {
+		element, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+
+		if _, ok := p.accept(closing); ok {
+			return elements, nil
+		}
+
+		err = p.expect(",")
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// checkBool returns a non-nil error if the given node is known to not evaluate
+// to a boolean value.
+func checkBool(n node, operator string) error {
+	kind := n.kind()
+	if kind != kindUnknown && kind != kindNull && kind != kindBool {
+		return newError(n.position(), "operator %s expects a boolean, got %s",
+			operator, kind)
+	}
+
+	return nil
+}
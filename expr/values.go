@@ -0,0 +1,506 @@
+package expr
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// valueKind is the kind of a value handled by expressions.
+type valueKind int
+
+const (
+	kindUnknown valueKind = iota
+	kindNull
+	kindBool
+	kindNumber
+	kindString
+	kindTime
+	kindDuration
+	kindList
+)
+
+func (k valueKind) String() string {
+	switch k {
+	case kindNull:
+		return "null"
+	case kindBool:
+		return "boolean"
+	case kindNumber:
+		return "number"
+	case kindString:
+		return "string"
+	case kindTime:
+		return "time"
+	case kindDuration:
+		return "duration"
+	case kindList:
+		return "list"
+	}
+
+	return "unknown"
+}
+
+// timeLayouts are the layouts accepted when comparing times with strings.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// normalize converts the given value to one of the types handled by
+// expressions: nil, bool, float64, string, time.Time, time.Duration or
+// []interface{}. Values of other types are returned as is.
+func normalize(value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case nil, bool, float64, string, time.Time, time.Duration:
+		return typedValue
+	case *time.Time:
+		if typedValue == nil {
+			return nil
+		}
+		return *typedValue
+	case fmt.Stringer:
+		reflectValue := reflect.ValueOf(value)
+		if reflectValue.Kind() == reflect.Ptr && reflectValue.IsNil() {
+			return nil
+		}
+		if reflectValue.Kind() == reflect.Struct ||
+			reflectValue.Kind() == reflect.Ptr {
+			// For example, *url.URL.
+			return typedValue.String()
+		}
+	}
+
+	reflectValue := reflect.ValueOf(value)
+	switch reflectValue.Kind() {
+	case reflect.Bool:
+		return reflectValue.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		return float64(reflectValue.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		return float64(reflectValue.Uint())
+	case reflect.Float32, reflect.Float64:
+		return reflectValue.Float()
+	case reflect.String:
+		return reflectValue.String()
+	case reflect.Slice, reflect.Array:
+		if reflectValue.Kind() == reflect.Slice && reflectValue.IsNil() {
+			return []interface{}{}
+		}
+		list := make([]interface{}, reflectValue.Len())
+		for i := range list {
+			list[i] = normalize(reflectValue.Index(i).Interface())
+		}
+		return list
+	case reflect.Ptr, reflect.Interface:
+		if reflectValue.IsNil() {
+			return nil
+		}
+		return normalize(reflectValue.Elem().Interface())
+	}
+
+	return value
+}
+
+func kindOf(value interface{}) valueKind {
+	switch value.(type) {
+	case nil:
+		return kindNull
+	case bool:
+		return kindBool
+	case float64:
+		return kindNumber
+	case string:
+		return kindString
+	case time.Time:
+		return kindTime
+	case time.Duration:
+		return kindDuration
+	case []interface{}:
+		return kindList
+	}
+
+	return kindUnknown
+}
+
+// ToString returns a string representation of the given expression value.
+// Integral numbers are formatted without decimals and times are formatted as
+// RFC 3339.
+func ToString(value interface{}) string {
+	switch typedValue := normalize(value).(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(typedValue, 'f', -1, 64)
+	case time.Time:
+		return typedValue.Format(time.RFC3339Nano)
+	case []interface{}:
+		elements := make([]string, len(typedValue))
+		for i, element := range typedValue {
+			elements[i] = ToString(element)
+		}
+		return strings.Join(elements, ",")
+	case string:
+		return typedValue
+	default:
+		return fmt.Sprint(typedValue)
+	}
+}
+
+// checkValues returns a non-nil error if any of the given values has a type
+// that can't be handled by expressions (for example, a struct payload or a map
+// metadata value).
+func checkValues(values ...interface{}) error {
+	for _, value := range values {
+		if kindOf(value) == kindUnknown {
+			return fmt.Errorf("unsupported value of type %T", value)
+		}
+	}
+
+	return nil
+}
+
+// checkComparison returns a non-nil error if values of the given kinds can't be
+// compared with the given operator.
+func checkComparison(operator string, left, right valueKind) error {
+	if left == kindUnknown || right == kindUnknown || left == kindNull ||
+		right == kindNull {
+		return nil
+	}
+
+	if (left == kindTime && right == kindString) ||
+		(left == kindString && right == kindTime) {
+		return nil
+	}
+
+	if left != right {
+		return fmt.Errorf("can't compare %s with %s", left, right)
+	}
+
+	if operator != "==" && operator != "!=" &&
+		(left == kindBool || left == kindList) {
+		return fmt.Errorf("operator %s can't be used with %ss", operator,
+			left)
+	}
+
+	return nil
+}
+
+// compare evaluates the given comparison operator. Comparisons involving null
+// values are false except for == and !=.
+func compare(operator string, left, right interface{}) (bool, error) {
+	if left == nil || right == nil {
+		switch operator {
+		case "==":
+			return left == nil && right == nil, nil
+		case "!=":
+			return !(left == nil && right == nil), nil
+		}
+		return false, nil
+	}
+
+	err := checkValues(left, right)
+	if err != nil {
+		return false, err
+	}
+
+	_, leftIsTime := left.(time.Time)
+	_, rightIsTime := right.(time.Time)
+	if leftIsTime && !rightIsTime {
+		right, err = toTime(right)
+	} else if rightIsTime && !leftIsTime {
+		left, err = toTime(left)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	err = checkComparison(operator, kindOf(left), kindOf(right))
+	if err != nil {
+		return false, err
+	}
+
+	mismatch := fmt.Errorf("can't compare %s with %s", kindOf(left),
+		kindOf(right))
+
+	var result int
+	switch typedLeft := left.(type) {
+	case bool:
+		typedRight, ok := right.(bool)
+		if !ok {
+			return false, mismatch
+		}
+		if typedLeft != typedRight {
+			result = 1
+		}
+	case float64:
+		typedRight, ok := right.(float64)
+		if !ok {
+			return false, mismatch
+		}
+		result = compareOrdered(typedLeft, typedRight)
+	case string:
+		typedRight, ok := right.(string)
+		if !ok {
+			return false, mismatch
+		}
+		result = strings.Compare(typedLeft, typedRight)
+	case time.Duration:
+		typedRight, ok := right.(time.Duration)
+		if !ok {
+			return false, mismatch
+		}
+		result = compareOrdered(typedLeft, typedRight)
+	case []interface{}:
+		if !reflect.DeepEqual(typedLeft, right) {
+			result = 1
+		}
+	case time.Time:
+		typedRight, ok := right.(time.Time)
+		if !ok {
+			return false, mismatch
+		}
+		switch {
+		case typedLeft.Before(typedRight):
+			result = -1
+		case typedLeft.After(typedRight):
+			result = 1
+		}
+	default:
+		return false, mismatch
+	}
+
+	switch operator {
+	case "==":
+		return result == 0, nil
+	case "!=":
+		return result != 0, nil
+	case "<":
+		return result < 0, nil
+	case "<=":
+		return result <= 0, nil
+	case ">":
+		return result > 0, nil
+	}
+
+	return result >= 0, nil
+}
+
+func compareOrdered[T float64 | time.Duration](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+func toTime(value interface{}) (interface{}, error) {
+	stringValue, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+
+	parsedTime, err := ParseTime(stringValue)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsedTime, nil
+}
+
+// ParseTime parses the given string as a time in RFC 3339,
+// "2006-01-02 15:04:05", "2006-01-02 15:04" or "2006-01-02" format. Times
+// without a time zone are in UTC.
+func ParseTime(text string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		parsedTime, err := time.Parse(layout, text)
+		if err == nil {
+			return parsedTime, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("can't parse %q as a time", text)
+}
+
+// arithmeticKind returns the kind of the result of applying the given
+// arithmetic operator to values of the given kinds or a non-nil error if the
+// operator can't be applied to them.
+func arithmeticKind(operator string, left, right valueKind) (valueKind, error) {
+	if left == kindUnknown || right == kindUnknown {
+		return kindUnknown, nil
+	}
+	if left == kindNull || right == kindNull {
+		return kindNull, nil
+	}
+
+	switch {
+	case left == kindNumber && right == kindNumber:
+		return kindNumber, nil
+	case left == kindDuration && right == kindDuration:
+		return kindDuration, nil
+	case left == kindTime && right == kindDuration:
+		return kindTime, nil
+	case operator == "+" && left == kindDuration && right == kindTime:
+		return kindTime, nil
+	case operator == "+" && left == kindString && right == kindString:
+		return kindString, nil
+	case operator == "-" && left == kindTime && right == kindTime:
+		return kindDuration, nil
+	}
+
+	return kindUnknown, fmt.Errorf("operator %s can't be used with %s and %s",
+		operator, left, right)
+}
+
+// arithmetic evaluates the given arithmetic operator. The result is null if
+// any of the values is null.
+func arithmetic(operator string, left, right interface{}) (interface{}, error) {
+	err := checkValues(left, right)
+	if err != nil {
+		return nil, err
+	}
+	if left == nil || right == nil {
+		return nil, nil
+	}
+
+	sign := 1
+	if operator == "-" {
+		sign = -1
+	}
+
+	switch typedLeft := left.(type) {
+	case float64:
+		if typedRight, ok := right.(float64); ok {
+			return typedLeft + float64(sign)*typedRight, nil
+		}
+	case string:
+		if typedRight, ok := right.(string); ok && operator == "+" {
+			return typedLeft + typedRight, nil
+		}
+	case time.Duration:
+		switch typedRight := right.(type) {
+		case time.Duration:
+			return typedLeft + time.Duration(sign)*typedRight, nil
+		case time.Time:
+			if operator == "+" {
+				return typedRight.Add(typedLeft), nil
+			}
+		}
+	case time.Time:
+		switch typedRight := right.(type) {
+		case time.Duration:
+			return typedLeft.Add(time.Duration(sign) * typedRight), nil
+		case time.Time:
+			if operator == "-" {
+				return typedLeft.Sub(typedRight), nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("operator %s can't be used with %s and %s",
+		operator, kindOf(left), kindOf(right))
+}
+
+// checkMatch returns a non-nil error if the given match operator can't be
+// applied to values of the given kinds.
+func checkMatch(operator string, left, right valueKind) error {
+	valid := func(kind valueKind, kinds ...valueKind) bool {
+		if kind == kindUnknown || kind == kindNull {
+			return true
+		}
+		for _, validKind := range kinds {
+			if kind == validKind {
+				return true
+			}
+		}
+		return false
+	}
+
+	var ok bool
+	switch operator {
+	case "contains":
+		ok = valid(left, kindString, kindList)
+	case "in":
+		ok = valid(right, kindString, kindList)
+	default:
+		ok = valid(left, kindString) && valid(right, kindString)
+	}
+
+	if !ok {
+		return fmt.Errorf("operator %s can't be used with %s and %s",
+			operator, left, right)
+	}
+
+	return nil
+}
+
+// match evaluates the given match operator (matches, contains, startswith,
+// endswith or in). The result is false if any of the values is null. The
+// given pattern, if not nil, is used instead of compiling the right value
+// for the matches operator.
+func match(operator string, left, right interface{},
+	pattern *regexp.Regexp) (bool, error) {
+	if left == nil || right == nil {
+		return false, nil
+	}
+
+	if operator == "in" {
+		operator = "contains"
+		left, right = right, left
+	}
+
+	err := checkValues(left, right)
+	if err != nil {
+		return false, err
+	}
+
+	err = checkMatch(operator, kindOf(left), kindOf(right))
+	if err != nil {
+		return false, err
+	}
+
+	if list, ok := left.([]interface{}); ok {
+		for _, element := range list {
+			equal, err := compare("==", element, right)
+			if err == nil && equal {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	leftString, ok := left.(string)
+	if !ok {
+		return false, fmt.Errorf("operator %s can't be used with %s and %s",
+			operator, kindOf(left), kindOf(right))
+	}
+	rightString := ToString(right)
+
+	switch operator {
+	case "matches":
+		if pattern == nil {
+			pattern, err = regexp.Compile(rightString)
+			if err != nil {
+				return false, fmt.Errorf("invalid regular expression "+
+					"%q : %v", rightString, err)
+			}
+		}
+		return pattern.MatchString(leftString), nil
+	case "contains":
+		return strings.Contains(leftString, rightString), nil
+	case "startswith":
+		return strings.HasPrefix(leftString, rightString), nil
+	}
+
+	return strings.HasSuffix(leftString, rightString), nil
+}
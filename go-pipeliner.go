@@ -6,12 +6,18 @@ import (
 	"fmt"
 
 	"github.com/brunoga/go-pipeliner/config"
+	"github.com/brunoga/go-pipeliner/state"
 
 	modules "gopkg.in/brunoga/go-modules.v1"
 )
 
 var configFile = flag.String("config", "./config.yaml", "path to config file")
 var listModules = flag.Bool("list-modules", false, "list available modules and exit")
+var stateFile = flag.String("state", "./pipeliner-state.json", "path to state file (empty disables state)")
+var debug = flag.Bool("debug", false, "enable debug logs")
+var explain = flag.Bool("explain", false, "print items dropped by processors and why")
+var explainFile = flag.String("explain-file", "", "path to file where items dropped by processors and why are appended")
+var fromScratch = flag.Bool("from-scratch", false, "ignore checkpoints saved by previous runs")
 
 func printModulesByType(moduleType string) {
 	fullModuleMap := modules.GetModulesByType(moduleType)
@@ -44,6 +50,19 @@ func main() {
 	if err != nil {
 		fmt.Println(err)
 	} else {
+		var stateStore *state.Store
+		if *stateFile != "" {
+			stateStore, err = state.OpenStore(*stateFile)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			config.SetStateStore(stateStore, *fromScratch)
+		}
+
+		config.SetDebug(*debug)
+		config.SetExplain(*explain, *explainFile)
+
 		fmt.Println("* Starting pipelines.")
 		config.Dump()
 		err := config.StartPipelines()
@@ -51,6 +70,14 @@ func main() {
 			fmt.Println(err)
 		}
 		config.WaitPipelines()
+
+		if stateStore != nil {
+			err = stateStore.Close()
+			if err != nil {
+				fmt.Println(err)
+			}
+		}
+
 		fmt.Println("* Pipelines done.")
 	}
 }
@@ -0,0 +1,96 @@
+// Package jsonlines reads and writes files with one JSON encoded value per
+// line. It is used by recordings and dead-letter files.
+package jsonlines
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// maxLineSize is the maximum size of a line that can be read.
+const maxLineSize = 16 * 1024 * 1024
+
+// Writer writes JSON encoded values to a file, one per line. It is safe for
+// concurrent use.
+type Writer struct {
+	mutex   sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+}
+
+// Create creates (or truncates) the file at the given path for writing.
+func Create(path string) (*Writer, error) {
+	return open(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC)
+}
+
+// Append opens (creating it if needed) the file at the given path for
+// appending.
+func Append(path string) (*Writer, error) {
+	return open(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND)
+}
+
+func open(path string, flag int) (*Writer, error) {
+	file, err := os.OpenFile(path, flag, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Writer{
+		file:    file,
+		encoder: json.NewEncoder(file),
+	}, nil
+}
+
+// Write appends the JSON encoding of the given value to the file.
+func (w *Writer) Write(value interface{}) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.encoder.Encode(value)
+}
+
+// Close closes the underlying file.
+func (w *Writer) Close() error {
+	return w.file.Close()
+}
+
+// ReadFile decodes each line of the file at the given path into a new value
+// returned by newValue and calls valueFunc with it, in the order they were
+// written. Empty lines are skipped. It stops at the first error returned by
+// valueFunc.
+func ReadFile(path string, newValue func() interface{},
+	valueFunc func(interface{}) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		value := newValue()
+		err := json.Unmarshal(scanner.Bytes(), value)
+		if err != nil {
+			return fmt.Errorf("%s:%d : %v", path, line, err)
+		}
+
+		err = valueFunc(value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
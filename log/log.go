@@ -7,12 +7,24 @@ import (
 type LogEntry struct {
 	Module base_modules.Module
 	Err    error
+	Debug  bool
 }
 
 func NewLogEntry(module base_modules.Module, err error) *LogEntry {
 	return &LogEntry{
 		module,
 		err,
+		false,
+	}
+}
+
+// NewDebugLogEntry creates a log entry that is only shown when debugging is
+// enabled.
+func NewDebugLogEntry(module base_modules.Module, err error) *LogEntry {
+	return &LogEntry{
+		module,
+		err,
+		true,
 	}
 }
 
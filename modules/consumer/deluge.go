@@ -2,9 +2,9 @@ package input
 
 import (
 	"fmt"
-	"sync"
 
 	"github.com/brunoga/go-pipeliner/datatypes"
+	"github.com/brunoga/go-pipeliner/retry"
 
 	deluge "github.com/brunoga/go-deluge"
 	pipeliner_modules "github.com/brunoga/go-pipeliner/modules"
@@ -23,7 +23,8 @@ func NewDelugeConsumerModule(specificId string) *DelugeConsumerModule {
 			"1.0.0", "deluge", specificId, nil),
 		nil,
 	}
-	delugeConsumerModule.SetConsumerFunc(delugeConsumerModule.sendItemToDeluge)
+	delugeConsumerModule.SetItemConsumerFunc(
+		delugeConsumerModule.sendItemToDeluge)
 
 	return delugeConsumerModule
 }
@@ -71,35 +72,32 @@ func (m *DelugeConsumerModule) Duplicate(specificId string) (base_modules.Module
 }
 
 func (m *DelugeConsumerModule) sendItemToDeluge(
-	consumerChannel <-chan *datatypes.PipelineItem,
-	waitGroup *sync.WaitGroup) {
-	defer waitGroup.Done()
-	for pipelineItem := range consumerChannel {
-		// Use first URL available.
-		// TODO(bga): Allow user to define preferred hosts or URL types
-		// (e.g. prefer magnet links).
-		torrentUrl, err := pipelineItem.GetUrl(0)
-		if err != nil {
-			// TODO(bga): Log error.
-			continue
-		}
-
-		// TODO(bga): Empty configuration for now.
-		options := map[string]interface{}{}
-
-		// TODO(bga): Handle errors when addding torrent.
-		switch torrentUrl.Scheme {
-		case "magnet":
-			m.delugeClient.CoreAddTorrentMagnet(
-				torrentUrl.String(), options)
-		case "http":
-			m.delugeClient.CoreAddTorrentUrl(
-				torrentUrl.String(), options)
-		default:
-			// TODO(bga): Add handling of other types.
-			continue
-		}
+	pipelineItem *datatypes.PipelineItem) error {
+	// Use first URL available.
+	// TODO(bga): Allow user to define preferred hosts or URL types
+	// (e.g. prefer magnet links).
+	torrentUrl, err := pipelineItem.GetUrl(0)
+	if err != nil {
+		return retry.Permanent(err)
+	}
+
+	// TODO(bga): Empty configuration for now.
+	options := map[string]interface{}{}
+
+	switch torrentUrl.Scheme {
+	case "magnet":
+		_, err = m.delugeClient.CoreAddTorrentMagnet(
+			torrentUrl.String(), options)
+	case "http":
+		_, err = m.delugeClient.CoreAddTorrentUrl(
+			torrentUrl.String(), options)
+	default:
+		// TODO(bga): Add handling of other types.
+		err = retry.Permanent(fmt.Errorf("unsupported URL scheme %q",
+			torrentUrl.Scheme))
 	}
+
+	return err
 }
 
 func init() {
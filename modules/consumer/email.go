@@ -114,23 +114,64 @@ func (m *EmailConsumerModule) sendEmail(
 	consumerChannel <-chan *datatypes.PipelineItem,
 	waitGroup *sync.WaitGroup) {
 	defer waitGroup.Done()
+
+	// Batch items (see the batch processor) are sent right away as a
+	// digest. Any other items are sent together when the input is closed.
+	var pipelineItems []*datatypes.PipelineItem
+	batches := 0
+	for pipelineItem := range consumerChannel {
+		batchItems, ok := pipelineItem.GetBatch()
+		if ok {
+			err := m.sendItems(batchItems)
+			if err != nil {
+				m.DeadLetter(pipelineItem, err)
+				pipelineItem.Nack(err)
+			} else {
+				pipelineItem.Ack()
+			}
+			batches++
+		} else {
+			pipelineItems = append(pipelineItems, pipelineItem)
+		}
+	}
+
+	if len(pipelineItems) > 0 || batches == 0 {
+		err := m.sendItems(pipelineItems)
+		for _, pipelineItem := range pipelineItems {
+			if err != nil {
+				m.DeadLetter(pipelineItem, err)
+				pipelineItem.Nack(err)
+			} else {
+				pipelineItem.Ack()
+			}
+		}
+	}
+}
+
+// sendItems sends an email with the given items. It returns a nil error on
+// success or a non-nil error on failure.
+func (m *EmailConsumerModule) sendItems(
+	pipelineItems []*datatypes.PipelineItem) error {
 	// Setup body.
 	body := "To: " + m.to + "\r\nSubject: " + m.subject + "\r\n\r\n"
 
 	// Add items to body.
-	i := 1
-	for pipelineItem := range consumerChannel {
-		body += fmt.Sprintf("%d : %v\r\n", i, pipelineItem)
-		i++
+	for i, pipelineItem := range pipelineItems {
+		body += fmt.Sprintf("%d : %v\r\n", i+1, pipelineItem)
 	}
 
 	// Send email.
-	err := smtp.SendMail(m.smtpServer, smtp.PlainAuth("", m.authUser,
-		m.authPassword, strings.Split(m.smtpServer, ":")[0]), m.from,
-		[]string{m.to}, []byte(body))
-	if err != nil {
-		// TODO(bga): Log error.
+	err := m.Retry(func() error {
+		return smtp.SendMail(m.smtpServer, smtp.PlainAuth("",
+			m.authUser, m.authPassword,
+			strings.Split(m.smtpServer, ":")[0]), m.from,
+			[]string{m.to}, []byte(body))
+	})
+	if err != nil && len(pipelineItems) == 0 {
+		m.Log(err)
 	}
+
+	return err
 }
 
 func init() {
@@ -48,6 +48,7 @@ func (m *PrintConsumerModule) printItem(
 	// received pipeline items.
 	for pipelineItem := range consumerChannel {
 		fmt.Println(pipelineItem)
+		pipelineItem.Ack()
 	}
 }
 
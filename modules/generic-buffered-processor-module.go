@@ -0,0 +1,184 @@
+package modules
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+)
+
+// GenericBufferedProcessorModule is a processor module that can hold items
+// and emit them later. Every item received is passed to the buffer function,
+// which returns the items that should be sent to the output right away (if
+// any). The flush function is called when the input is closed (with final set
+// to true) and, if a flush interval is set, periodically (with final set to
+// false). It returns the items that should be sent to the output at that
+// point.
+type GenericBufferedProcessorModule struct {
+	*GenericPipelineModule
+
+	inputChannel  chan *datatypes.PipelineItem
+	outputChannel chan<- *datatypes.PipelineItem
+
+	bufferFunc func(*datatypes.PipelineItem) []*datatypes.PipelineItem
+	flushFunc  func(bool) []*datatypes.PipelineItem
+
+	flushInterval time.Duration
+}
+
+func NewGenericBufferedProcessorModule(name, version, genericId,
+	specificId string,
+	bufferFunc func(*datatypes.PipelineItem) []*datatypes.PipelineItem,
+	flushFunc func(bool) []*datatypes.PipelineItem) *GenericBufferedProcessorModule {
+	return &GenericBufferedProcessorModule{
+		NewGenericPipelineModule(name, version, genericId, specificId,
+			"pipeliner-processor"),
+		make(chan *datatypes.PipelineItem),
+		nil,
+		bufferFunc,
+		flushFunc,
+		0,
+	}
+}
+
+func (m *GenericBufferedProcessorModule) GetInputChannel() chan<- *datatypes.PipelineItem {
+	return m.inputChannel
+}
+
+func (m *GenericBufferedProcessorModule) SetOutputChannel(
+	outputChannel chan<- *datatypes.PipelineItem) error {
+	if outputChannel == nil {
+		return fmt.Errorf("can't set output to a nil channel")
+	}
+
+	m.outputChannel = outputChannel
+
+	return nil
+}
+
+// SetFlushInterval sets the interval between periodic calls to the flush
+// function. Zero (the default) means the flush function is only called when
+// the input is closed.
+func (m *GenericBufferedProcessorModule) SetFlushInterval(
+	flushInterval time.Duration) error {
+	if flushInterval < 0 {
+		return fmt.Errorf("flush interval must not be negative")
+	}
+
+	m.flushInterval = flushInterval
+
+	return nil
+}
+
+// GetFlushInterval returns the interval between periodic calls to the flush
+// function.
+func (m *GenericBufferedProcessorModule) GetFlushInterval() time.Duration {
+	return m.flushInterval
+}
+
+func (m *GenericBufferedProcessorModule) Start(waitGroup *sync.WaitGroup) error {
+	if !m.Ready() {
+		waitGroup.Done()
+		return fmt.Errorf("not ready")
+	}
+
+	if m.inputChannel == nil {
+		waitGroup.Done()
+		return fmt.Errorf("input channel not connected")
+	}
+
+	if m.outputChannel == nil {
+		waitGroup.Done()
+		return fmt.Errorf("output channel not connected")
+	}
+
+	if m.bufferFunc == nil {
+		waitGroup.Done()
+		return fmt.Errorf("buffer function must not be nil")
+	}
+
+	if m.flushFunc == nil {
+		waitGroup.Done()
+		return fmt.Errorf("flush function must not be nil")
+	}
+
+	go m.doWork(waitGroup)
+
+	return nil
+}
+
+func (m *GenericBufferedProcessorModule) SetBufferFunc(
+	bufferFunc func(*datatypes.PipelineItem) []*datatypes.PipelineItem) error {
+	if bufferFunc == nil {
+		return fmt.Errorf("buffer function must not be nil")
+	}
+
+	m.bufferFunc = bufferFunc
+
+	return nil
+}
+
+func (m *GenericBufferedProcessorModule) SetFlushFunc(
+	flushFunc func(bool) []*datatypes.PipelineItem) error {
+	if flushFunc == nil {
+		return fmt.Errorf("flush function must not be nil")
+	}
+
+	m.flushFunc = flushFunc
+
+	return nil
+}
+
+func (m *GenericBufferedProcessorModule) doWork(waitGroup *sync.WaitGroup) {
+	defer waitGroup.Done()
+	defer m.closeRecording()
+
+	quitChannel := m.quitChannel
+
+	var tickChannel <-chan time.Time
+	if m.flushInterval > 0 {
+		ticker := time.NewTicker(m.flushInterval)
+		defer ticker.Stop()
+		tickChannel = ticker.C
+	}
+L:
+	for {
+		select {
+		case item, ok := <-m.inputChannel:
+			if ok {
+				if !m.emit(m.bufferFunc(item), quitChannel) {
+					break L
+				}
+			} else {
+				if m.emit(m.flushFunc(true), quitChannel) {
+					close(m.outputChannel)
+				}
+				break L
+			}
+		case <-tickChannel:
+			if !m.emit(m.flushFunc(false), quitChannel) {
+				break L
+			}
+		case <-quitChannel:
+			break L
+		}
+	}
+}
+
+// emit sends the given items to the output. It returns false if the module was
+// stopped while doing so.
+func (m *GenericBufferedProcessorModule) emit(items []*datatypes.PipelineItem,
+	quitChannel <-chan struct{}) bool {
+	for _, item := range items {
+		m.AddHistory(item, datatypes.ActionPassed, "")
+		m.record(item)
+		select {
+		case m.outputChannel <- item:
+		case <-quitChannel:
+			return false
+		}
+	}
+
+	return true
+}
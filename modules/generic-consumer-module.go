@@ -1,6 +1,7 @@
 package modules
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 
@@ -13,6 +14,8 @@ type GenericConsumerModule struct {
 	inputChannel chan *datatypes.PipelineItem
 
 	consumerFunc func(<-chan *datatypes.PipelineItem, *sync.WaitGroup)
+
+	itemConsumerFunc func(*datatypes.PipelineItem) error
 }
 
 func NewGenericConsumerModule(name, version, genericId, specificId string,
@@ -23,6 +26,7 @@ func NewGenericConsumerModule(name, version, genericId, specificId string,
 			"pipeliner-consumer"),
 		make(chan *datatypes.PipelineItem),
 		consumerFunc,
+		nil,
 	}
 }
 
@@ -57,7 +61,42 @@ func (m *GenericConsumerModule) SetConsumerFunc(
 	return nil
 }
 
+// SetItemConsumerFunc sets a function that consumes a single item. This is an
+// alternative to SetConsumerFunc for consumers that handle each item
+// independently. Items for which itemConsumerFunc fails are retried according
+// to the module retry policy and reported as dead letters if they still fail.
+func (m *GenericConsumerModule) SetItemConsumerFunc(
+	itemConsumerFunc func(*datatypes.PipelineItem) error) error {
+	if itemConsumerFunc == nil {
+		return fmt.Errorf("item consumer function must not be nil")
+	}
+
+	m.itemConsumerFunc = itemConsumerFunc
+	m.consumerFunc = m.consumeItems
+
+	return nil
+}
+
+func (m *GenericConsumerModule) consumeItems(
+	consumerChannel <-chan *datatypes.PipelineItem,
+	waitGroup *sync.WaitGroup) {
+	defer waitGroup.Done()
+	for pipelineItem := range consumerChannel {
+		err := m.Retry(func() error {
+			return m.itemConsumerFunc(pipelineItem)
+		})
+		if err != nil {
+			m.DeadLetter(pipelineItem, err)
+			pipelineItem.Nack(err)
+		} else {
+			pipelineItem.Ack()
+		}
+	}
+}
+
 func (m *GenericConsumerModule) doWork(waitGroup *sync.WaitGroup) {
+	defer m.closeRecording()
+
 	consumerChannel := make(chan *datatypes.PipelineItem)
 
 	go m.consumerFunc(consumerChannel, waitGroup)
@@ -66,6 +105,9 @@ L:
 		select {
 		case pipelineItem, ok := <-m.inputChannel:
 			if ok {
+				m.AddHistory(pipelineItem, datatypes.ActionConsumed, "")
+				m.Debug(errors.New(pipelineItem.Lineage()))
+				m.record(pipelineItem)
 				consumerChannel <- pipelineItem
 			} else {
 				close(consumerChannel)
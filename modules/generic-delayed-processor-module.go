@@ -0,0 +1,179 @@
+package modules
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+)
+
+// GenericDelayedProcessorModule is a processor module that holds items for
+// some time before sending them to the output. The delay function is called
+// for each item received and returns how long it must be held. Items are held
+// independently, so an item with a long delay does not hold back items
+// received after it with shorter delays. Held items are discarded if the
+// module is stopped.
+type GenericDelayedProcessorModule struct {
+	*GenericPipelineModule
+
+	inputChannel  chan *datatypes.PipelineItem
+	outputChannel chan<- *datatypes.PipelineItem
+
+	delayFunc func(*datatypes.PipelineItem) time.Duration
+
+	maxPending int
+}
+
+func NewGenericDelayedProcessorModule(name, version, genericId,
+	specificId string,
+	delayFunc func(*datatypes.PipelineItem) time.Duration) *GenericDelayedProcessorModule {
+	return &GenericDelayedProcessorModule{
+		NewGenericPipelineModule(name, version, genericId, specificId,
+			"pipeliner-processor"),
+		make(chan *datatypes.PipelineItem),
+		nil,
+		delayFunc,
+		1000,
+	}
+}
+
+func (m *GenericDelayedProcessorModule) GetInputChannel() chan<- *datatypes.PipelineItem {
+	return m.inputChannel
+}
+
+func (m *GenericDelayedProcessorModule) SetOutputChannel(
+	outputChannel chan<- *datatypes.PipelineItem) error {
+	if outputChannel == nil {
+		return fmt.Errorf("can't set output to a nil channel")
+	}
+
+	m.outputChannel = outputChannel
+
+	return nil
+}
+
+// SetMaxPending sets the maximum number of items that can be held at the same
+// time. When it is reached, no more items are received until one of the held
+// items is sent. The default is 1000.
+func (m *GenericDelayedProcessorModule) SetMaxPending(maxPending int) error {
+	if maxPending < 1 {
+		return fmt.Errorf("maximum number of pending items must be at " +
+			"least 1")
+	}
+
+	m.maxPending = maxPending
+
+	return nil
+}
+
+func (m *GenericDelayedProcessorModule) Start(waitGroup *sync.WaitGroup) error {
+	if !m.Ready() {
+		waitGroup.Done()
+		return fmt.Errorf("not ready")
+	}
+
+	if m.inputChannel == nil {
+		waitGroup.Done()
+		return fmt.Errorf("input channel not connected")
+	}
+
+	if m.outputChannel == nil {
+		waitGroup.Done()
+		return fmt.Errorf("output channel not connected")
+	}
+
+	if m.delayFunc == nil {
+		waitGroup.Done()
+		return fmt.Errorf("delay function must not be nil")
+	}
+
+	go m.doWork(waitGroup)
+
+	return nil
+}
+
+func (m *GenericDelayedProcessorModule) SetDelayFunc(
+	delayFunc func(*datatypes.PipelineItem) time.Duration) error {
+	if delayFunc == nil {
+		return fmt.Errorf("delay function must not be nil")
+	}
+
+	m.delayFunc = delayFunc
+
+	return nil
+}
+
+func (m *GenericDelayedProcessorModule) doWork(waitGroup *sync.WaitGroup) {
+	defer waitGroup.Done()
+	defer m.closeRecording()
+
+	quitChannel := m.quitChannel
+
+	// Each held item takes a slot until it is sent.
+	pendingChannel := make(chan struct{}, m.maxPending)
+
+	var pendingWaitGroup sync.WaitGroup
+L:
+	for {
+		select {
+		case item, ok := <-m.inputChannel:
+			if !ok {
+				break L
+			}
+
+			delay := m.delayFunc(item)
+			if delay <= 0 {
+				if !m.emit(item, quitChannel) {
+					break L
+				}
+				continue
+			}
+
+			select {
+			case pendingChannel <- struct{}{}:
+			case <-quitChannel:
+				break L
+			}
+
+			pendingWaitGroup.Add(1)
+			go func() {
+				defer pendingWaitGroup.Done()
+				defer func() { <-pendingChannel }()
+
+				timer := time.NewTimer(delay)
+				select {
+				case <-timer.C:
+					m.emit(item, quitChannel)
+				case <-quitChannel:
+					timer.Stop()
+				}
+			}()
+		case <-quitChannel:
+			break L
+		}
+	}
+
+	pendingWaitGroup.Wait()
+
+	select {
+	case <-quitChannel:
+	default:
+		close(m.outputChannel)
+	}
+}
+
+// emit sends the given item to the output. It returns false if the module was
+// stopped while doing so.
+func (m *GenericDelayedProcessorModule) emit(item *datatypes.PipelineItem,
+	quitChannel <-chan struct{}) bool {
+	m.AddHistory(item, datatypes.ActionPassed, "")
+	m.record(item)
+	select {
+	case m.outputChannel <- item:
+	case <-quitChannel:
+		return false
+	}
+
+	return true
+}
@@ -1,7 +1,17 @@
 package modules
 
 import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+	"github.com/brunoga/go-pipeliner/deadletter"
+	"github.com/brunoga/go-pipeliner/explain"
 	"github.com/brunoga/go-pipeliner/log"
+	"github.com/brunoga/go-pipeliner/recording"
+	"github.com/brunoga/go-pipeliner/retry"
 
 	base_modules "gopkg.in/brunoga/go-modules.v1"
 )
@@ -9,23 +19,48 @@ import (
 type GenericPipelineModule struct {
 	*base_modules.GenericModule
 
-	quitChannel chan struct{}
-	logChannel  chan<- *log.LogEntry
+	quitChannel       chan struct{}
+	stopOnce          sync.Once
+	logChannel        chan<- *log.LogEntry
+	deadLetterChannel chan<- *deadletter.Entry
+	dropChannel       chan<- *explain.Entry
+
+	retryPolicy *retry.Policy
+
+	recordMutex sync.Mutex
+	recordPath  string
+	recorder    *recording.Writer
+
+	pipelineName string
 }
 
 func NewGenericPipelineModule(name, version, genericId, specificId,
 	moduleType string) *GenericPipelineModule {
 	return &GenericPipelineModule{
-		base_modules.NewGenericModule(name, version,
+		GenericModule: base_modules.NewGenericModule(name, version,
 			genericId, specificId, moduleType),
-		make(chan struct{}),
-		nil,
+		quitChannel:       make(chan struct{}),
+		logChannel:        nil,
+		deadLetterChannel: nil,
+		dropChannel:       nil,
+		retryPolicy:       retry.NewPolicy(),
+		recordPath:        "",
+		recorder:          nil,
+		pipelineName:      "",
 	}
 }
 
+// Stop stops the module. Stopped modules can not be started again.
 func (m *GenericPipelineModule) Stop() {
-	close(m.quitChannel)
-	m.quitChannel = make(chan struct{})
+	m.stopOnce.Do(func() {
+		close(m.quitChannel)
+	})
+}
+
+// Quit returns a channel that is closed when the module is stopped. Modules
+// that wait while handling an item must stop waiting when it is closed.
+func (m *GenericPipelineModule) Quit() <-chan struct{} {
+	return m.quitChannel
 }
 
 func (m *GenericPipelineModule) SetLogChannel(
@@ -38,3 +73,126 @@ func (m *GenericPipelineModule) Log(err error) {
 		m.logChannel <- log.NewLogEntry(m, err)
 	}
 }
+
+// Debug logs the given error only if debugging is enabled for the pipeline.
+func (m *GenericPipelineModule) Debug(err error) {
+	if m.logChannel != nil {
+		m.logChannel <- log.NewDebugLogEntry(m, err)
+	}
+}
+
+// SetPipelineName sets the name of the pipeline this module is part of.
+func (m *GenericPipelineModule) SetPipelineName(pipelineName string) {
+	m.pipelineName = pipelineName
+}
+
+// GetPipelineName returns the name of the pipeline this module is part of.
+func (m *GenericPipelineModule) GetPipelineName() string {
+	return m.pipelineName
+}
+
+// AddHistory records that the given action was taken on the given item by
+// this module.
+func (m *GenericPipelineModule) AddHistory(item *datatypes.PipelineItem,
+	action, detail string) {
+	item.AddHistory(datatypes.HistoryEntry{
+		Time:       time.Now(),
+		GenericId:  m.GenericId(),
+		SpecificId: m.SpecificId(),
+		Action:     action,
+		Detail:     detail,
+	})
+}
+
+func (m *GenericPipelineModule) SetDropChannel(
+	dropChannel chan<- *explain.Entry) {
+	m.dropChannel = dropChannel
+}
+
+// Drop records that the given item was dropped from the pipeline by this
+// module for the given reason, reports it to the pipeline and acknowledges it
+// as dropped (see datatypes.PipelineItem.AckDropped).
+func (m *GenericPipelineModule) Drop(item *datatypes.PipelineItem,
+	reason string) {
+	m.AddHistory(item, datatypes.ActionDropped, reason)
+	m.Debug(errors.New(item.Lineage()))
+
+	if m.dropChannel != nil {
+		m.dropChannel <- explain.NewEntry(m, item, reason)
+	}
+
+	item.AckDropped()
+}
+
+func (m *GenericPipelineModule) SetDeadLetterChannel(
+	deadLetterChannel chan<- *deadletter.Entry) {
+	m.deadLetterChannel = deadLetterChannel
+}
+
+// DeadLetter reports that the given item could not be handled because of the
+// given error. If no dead-letter channel is set, the error is logged instead.
+func (m *GenericPipelineModule) DeadLetter(item *datatypes.PipelineItem,
+	err error) {
+	if m.deadLetterChannel != nil {
+		m.deadLetterChannel <- deadletter.NewEntry(m, item, err)
+	} else {
+		m.Log(fmt.Errorf("%q : %v", item.GetName(), err))
+	}
+}
+
+// RetryPolicy returns the retry policy used by Retry. It can be changed in
+// place to configure retries.
+func (m *GenericPipelineModule) RetryPolicy() *retry.Policy {
+	return m.retryPolicy
+}
+
+// Retry calls the given operation, retrying it according to the module retry
+// policy. Retries are aborted if the module is stopped.
+func (m *GenericPipelineModule) Retry(operation func() error) error {
+	return m.retryPolicy.Do(m.quitChannel, operation)
+}
+
+// SetRecordPath sets the path to a file where all items that leave the module
+// (or, for consumers, that reach it) are recorded. Recordings can be replayed
+// with the replay producer module.
+func (m *GenericPipelineModule) SetRecordPath(recordPath string) {
+	m.recordPath = recordPath
+}
+
+// record writes the given item to the recording file, if one was set.
+func (m *GenericPipelineModule) record(item *datatypes.PipelineItem) {
+	m.recordMutex.Lock()
+	defer m.recordMutex.Unlock()
+
+	if m.recordPath == "" {
+		return
+	}
+
+	if m.recorder == nil {
+		recorder, err := recording.NewWriter(m.recordPath)
+		if err != nil {
+			// Do not try again.
+			m.recordPath = ""
+			m.Log(err)
+			return
+		}
+
+		m.recorder = recorder
+	}
+
+	err := m.recorder.Write(item)
+	if err != nil {
+		m.Log(err)
+	}
+}
+
+// closeRecording closes the recording file, if one is open.
+func (m *GenericPipelineModule) closeRecording() {
+	m.recordMutex.Lock()
+	defer m.recordMutex.Unlock()
+
+	if m.recorder != nil {
+		m.recorder.Close()
+		m.recorder = nil
+	}
+}
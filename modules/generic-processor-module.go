@@ -7,23 +7,32 @@ import (
 	"github.com/brunoga/go-pipeliner/datatypes"
 )
 
+// GenericProcessorModule is a processor module that calls a processor function
+// for each item it receives. The processor function returns true and a reason
+// if the item should be dropped from the pipeline or false and an empty string
+// if it should be sent to the output.
 type GenericProcessorModule struct {
 	*GenericPipelineModule
 
 	inputChannel  chan *datatypes.PipelineItem
 	outputChannel chan<- *datatypes.PipelineItem
 
-	processorFunc func(*datatypes.PipelineItem) bool
+	processorFunc func(*datatypes.PipelineItem) (bool, string)
+
+	workers int
+	ordered bool
 }
 
 func NewGenericProcessorModule(name, version, genericId, specificId string,
-	processorFunc func(*datatypes.PipelineItem) bool) *GenericProcessorModule {
+	processorFunc func(*datatypes.PipelineItem) (bool, string)) *GenericProcessorModule {
 	return &GenericProcessorModule{
 		NewGenericPipelineModule(name, version, genericId, specificId,
 			"pipeliner-processor"),
 		make(chan *datatypes.PipelineItem),
 		nil,
 		processorFunc,
+		1,
+		false,
 	}
 }
 
@@ -42,6 +51,24 @@ func (m *GenericProcessorModule) SetOutputChannel(
 	return nil
 }
 
+// SetWorkers sets the number of concurrent invocations of the processor
+// function. The default is 1 (items are processed one at a time).
+func (m *GenericProcessorModule) SetWorkers(workers int) error {
+	if workers < 1 {
+		return fmt.Errorf("number of workers must be at least 1")
+	}
+
+	m.workers = workers
+
+	return nil
+}
+
+// SetOrdered sets if items must be sent to the output in the same order they
+// were received when more than one worker is used.
+func (m *GenericProcessorModule) SetOrdered(ordered bool) {
+	m.ordered = ordered
+}
+
 func (m *GenericProcessorModule) Start(waitGroup *sync.WaitGroup) error {
 	if !m.Ready() {
 		waitGroup.Done()
@@ -63,13 +90,17 @@ func (m *GenericProcessorModule) Start(waitGroup *sync.WaitGroup) error {
 		return fmt.Errorf("processor function must not be nil")
 	}
 
-	go m.doWork(waitGroup)
+	if m.workers > 1 {
+		go m.doParallelWork(waitGroup)
+	} else {
+		go m.doWork(waitGroup)
+	}
 
 	return nil
 }
 
 func (m *GenericProcessorModule) SetProcessorFunc(
-	processorFunc func(*datatypes.PipelineItem) bool) error {
+	processorFunc func(*datatypes.PipelineItem) (bool, string)) error {
 	if processorFunc == nil {
 		return fmt.Errorf("processor function must not be nil")
 	}
@@ -81,21 +112,170 @@ func (m *GenericProcessorModule) SetProcessorFunc(
 
 func (m *GenericProcessorModule) doWork(waitGroup *sync.WaitGroup) {
 	defer waitGroup.Done()
+	defer m.closeRecording()
+
+	quitChannel := m.quitChannel
 L:
 	for {
 		select {
 		case item, ok := <-m.inputChannel:
 			if ok {
-				filtered := m.processorFunc(item)
+				filtered := m.process(item)
 				if !filtered {
-					m.outputChannel <- item
+					m.record(item)
+					select {
+					case m.outputChannel <- item:
+					case <-quitChannel:
+						break L
+					}
 				}
 			} else {
 				close(m.outputChannel)
 				break L
 			}
-		case <-m.quitChannel:
+		case <-quitChannel:
+			break L
+		}
+	}
+}
+
+// processorJob is an item being processed by a worker. The result of the
+// processor function is sent to the (buffered) filtered channel so in-order
+// output can wait for it.
+type processorJob struct {
+	item     *datatypes.PipelineItem
+	filtered chan bool
+}
+
+func (m *GenericProcessorModule) doParallelWork(waitGroup *sync.WaitGroup) {
+	defer waitGroup.Done()
+	defer m.closeRecording()
+
+	quitChannel := m.quitChannel
+
+	jobChannel := make(chan *processorJob)
+
+	// Jobs are queued here in input order. This is only used when ordered
+	// output was requested and its size limits how many items can be in
+	// flight.
+	var pendingChannel chan *processorJob
+	if m.ordered {
+		pendingChannel = make(chan *processorJob, m.workers)
+	}
+
+	var workersWaitGroup sync.WaitGroup
+	for i := 0; i < m.workers; i++ {
+		workersWaitGroup.Add(1)
+		go m.worker(jobChannel, &workersWaitGroup, quitChannel)
+	}
+
+	var emitterWaitGroup sync.WaitGroup
+	if m.ordered {
+		emitterWaitGroup.Add(1)
+		go m.orderedEmitter(pendingChannel, &emitterWaitGroup,
+			quitChannel)
+	}
+
+	stopped := false
+L:
+	for {
+		select {
+		case item, ok := <-m.inputChannel:
+			if !ok {
+				break L
+			}
+
+			job := &processorJob{item, make(chan bool, 1)}
+
+			if m.ordered {
+				select {
+				case pendingChannel <- job:
+				case <-quitChannel:
+					stopped = true
+					break L
+				}
+			}
+
+			select {
+			case jobChannel <- job:
+			case <-quitChannel:
+				stopped = true
+				break L
+			}
+		case <-quitChannel:
+			stopped = true
 			break L
 		}
 	}
+
+	close(jobChannel)
+	workersWaitGroup.Wait()
+
+	if m.ordered {
+		close(pendingChannel)
+		emitterWaitGroup.Wait()
+	}
+
+	if !stopped {
+		close(m.outputChannel)
+	}
+}
+
+func (m *GenericProcessorModule) worker(jobChannel <-chan *processorJob,
+	waitGroup *sync.WaitGroup, quitChannel <-chan struct{}) {
+	defer waitGroup.Done()
+	for job := range jobChannel {
+		filtered := m.process(job.item)
+		if m.ordered {
+			job.filtered <- filtered
+		} else if !filtered {
+			m.record(job.item)
+			select {
+			case m.outputChannel <- job.item:
+			case <-quitChannel:
+			}
+		}
+	}
+}
+
+func (m *GenericProcessorModule) orderedEmitter(
+	pendingChannel <-chan *processorJob, waitGroup *sync.WaitGroup,
+	quitChannel <-chan struct{}) {
+	defer waitGroup.Done()
+	for job := range pendingChannel {
+		var filtered bool
+		select {
+		case filtered = <-job.filtered:
+		case <-quitChannel:
+			continue
+		}
+
+		if !filtered {
+			m.record(job.item)
+			select {
+			case m.outputChannel <- job.item:
+			case <-quitChannel:
+			}
+		}
+	}
+}
+
+// process calls the processor function for the given item and records what
+// happened to it in its history. Filtered items are dropped.
+func (m *GenericProcessorModule) process(item *datatypes.PipelineItem) bool {
+	revision := item.Revision()
+
+	filtered, reason := m.processorFunc(item)
+	if filtered {
+		m.Drop(item, reason)
+		return true
+	}
+
+	if item.Revision() != revision {
+		m.AddHistory(item, datatypes.ActionModified, "")
+	} else {
+		m.AddHistory(item, datatypes.ActionPassed, "")
+	}
+
+	return false
 }
@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"github.com/brunoga/go-pipeliner/datatypes"
+	"github.com/brunoga/go-pipeliner/state"
 )
 
 type GenericProducerModule struct {
@@ -13,6 +14,10 @@ type GenericProducerModule struct {
 	outputChannel chan<- *datatypes.PipelineItem
 
 	producerFunc func(chan<- *datatypes.PipelineItem, <-chan struct{})
+
+	checkpoint *state.Checkpoint
+
+	fingerprintFields []string
 }
 
 func NewGenericProducerModule(name, version, genericId, specificId string,
@@ -23,6 +28,8 @@ func NewGenericProducerModule(name, version, genericId, specificId string,
 			"pipeliner-producer"),
 		nil,
 		producerFunc,
+		nil,
+		nil,
 	}
 }
 
@@ -37,6 +44,29 @@ func (m *GenericProducerModule) SetOutputChannel(
 	return nil
 }
 
+func (m *GenericProducerModule) SetCheckpoint(checkpoint *state.Checkpoint) {
+	m.checkpoint = checkpoint
+}
+
+// Checkpoint returns the checkpoint producers can use to resume from where a
+// previous run stopped. It might be nil, which is still safe to use.
+func (m *GenericProducerModule) Checkpoint() *state.Checkpoint {
+	return m.checkpoint
+}
+
+// SetFingerprintFields sets the fields used to compute the fingerprint of items
+// produced by this module (see datatypes.PipelineItem.ComputeFingerprint).
+func (m *GenericProducerModule) SetFingerprintFields(fields []string) error {
+	_, err := datatypes.NewPipelineItem("").ComputeFingerprint(fields...)
+	if err != nil {
+		return err
+	}
+
+	m.fingerprintFields = fields
+
+	return nil
+}
+
 func (m *GenericProducerModule) Start(waitGroup *sync.WaitGroup) error {
 	if !m.Ready() {
 		waitGroup.Done()
@@ -71,6 +101,7 @@ func (m *GenericProducerModule) SetProducerFunc(
 
 func (m *GenericProducerModule) doWork(waitGroup *sync.WaitGroup) {
 	defer waitGroup.Done()
+	defer m.closeRecording()
 	producerChannel := make(chan *datatypes.PipelineItem)
 	producerControlChannel := make(chan struct{})
 
@@ -81,6 +112,8 @@ L:
 		select {
 		case item, ok := <-producerChannel:
 			if ok {
+				m.setupItem(item)
+				m.record(item)
 				m.outputChannel <- item
 			} else {
 				close(m.outputChannel)
@@ -92,3 +125,24 @@ L:
 		}
 	}
 }
+
+// setupItem sets the origin and fingerprint of a newly produced item.
+func (m *GenericProducerModule) setupItem(item *datatypes.PipelineItem) {
+	item.SetOrigin(datatypes.Origin{
+		Pipeline:   m.GetPipelineName(),
+		GenericId:  m.GenericId(),
+		SpecificId: m.SpecificId(),
+	})
+
+	if item.GetFingerprint() == "" {
+		fingerprint, err := item.ComputeFingerprint(
+			m.fingerprintFields...)
+		if err != nil {
+			m.Log(err)
+		} else {
+			item.SetFingerprint(fingerprint)
+		}
+	}
+
+	m.AddHistory(item, datatypes.ActionProduced, "")
+}
@@ -1,7 +1,10 @@
 package modules
 
 import (
+	"time"
+
 	"github.com/brunoga/go-pipeliner/pipeline"
+	"github.com/brunoga/go-pipeliner/retry"
 
 	base_modules "gopkg.in/brunoga/go-modules.v1"
 )
@@ -30,6 +33,48 @@ type PipelinerConsumerModule interface {
 	pipeline.ConsumerNode
 }
 
+// WorkersSetter is implemented by modules that can process items
+// concurrently.
+type WorkersSetter interface {
+	SetWorkers(workers int) error
+}
+
+// OrderedSetter is implemented by modules that can preserve the order of
+// items when processing them concurrently.
+type OrderedSetter interface {
+	SetOrdered(ordered bool)
+}
+
+// FlushIntervalSetter is implemented by modules that buffer items and can
+// periodically flush them.
+type FlushIntervalSetter interface {
+	SetFlushInterval(flushInterval time.Duration) error
+}
+
+// MaxPendingSetter is implemented by modules that hold items for some time
+// and can limit how many of them are held at once.
+type MaxPendingSetter interface {
+	SetMaxPending(maxPending int) error
+}
+
+// Retrier is implemented by modules that can retry failed operations. The
+// returned policy can be changed in place.
+type Retrier interface {
+	RetryPolicy() *retry.Policy
+}
+
+// RecordPathSetter is implemented by modules that can record the items going
+// through them.
+type RecordPathSetter interface {
+	SetRecordPath(recordPath string)
+}
+
+// FingerprintFieldsSetter is implemented by modules that can compute
+// fingerprints for the items they produce.
+type FingerprintFieldsSetter interface {
+	SetFingerprintFields(fields []string) error
+}
+
 // RegisterPipelinerProducerModule registers a Pipeliner producer module.
 func RegisterPipelinerProducerModule(module PipelinerProducerModule) error {
 	return base_modules.RegisterModule(module)
@@ -0,0 +1,182 @@
+package input
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+	"github.com/brunoga/go-pipeliner/expr"
+
+	pipeliner_modules "github.com/brunoga/go-pipeliner/modules"
+	base_modules "gopkg.in/brunoga/go-modules.v1"
+)
+
+// Ways to handle items without a date.
+const (
+	missingDateKeep = "keep"
+	missingDateDrop = "drop"
+	missingDateNow  = "now"
+)
+
+// AgeProcessorModule lets through only items with dates inside a window. The
+// window can be relative to the current time (max_age and min_age) and/or
+// absolute (after and before). Items without a date are kept, dropped or
+// considered to be from now depending on the missing parameter.
+type AgeProcessorModule struct {
+	*pipeliner_modules.GenericProcessorModule
+
+	maxAge      time.Duration
+	minAge      time.Duration
+	after       time.Time
+	before      time.Time
+	missingDate string
+}
+
+func NewAgeProcessorModule(specificId string) *AgeProcessorModule {
+	ageProcessorModule := &AgeProcessorModule{
+		pipeliner_modules.NewGenericProcessorModule(
+			"Age Processor Module", "1.0.0", "age",
+			specificId, nil),
+		0,
+		0,
+		time.Time{},
+		time.Time{},
+		missingDateKeep,
+	}
+	ageProcessorModule.SetProcessorFunc(ageProcessorModule.filterItem)
+
+	return ageProcessorModule
+}
+
+func (m *AgeProcessorModule) Configure(params *base_modules.ParameterMap) error {
+	var err error
+
+	maxAge := time.Duration(0)
+	maxAgeParam := (*params)["max_age"]
+	if maxAgeParam != "" {
+		maxAge, err = expr.ParseDuration(maxAgeParam)
+		if err != nil || maxAge <= 0 {
+			return fmt.Errorf("invalid max_age parameter %q", maxAgeParam)
+		}
+	}
+
+	minAge := time.Duration(0)
+	minAgeParam := (*params)["min_age"]
+	if minAgeParam != "" {
+		minAge, err = expr.ParseDuration(minAgeParam)
+		if err != nil || minAge <= 0 {
+			return fmt.Errorf("invalid min_age parameter %q", minAgeParam)
+		}
+	}
+
+	if maxAge != 0 && minAge >= maxAge {
+		return fmt.Errorf("min_age must be less than max_age")
+	}
+
+	after := time.Time{}
+	afterParam := (*params)["after"]
+	if afterParam != "" {
+		after, err = expr.ParseTime(afterParam)
+		if err != nil {
+			return fmt.Errorf("invalid after parameter : %v", err)
+		}
+	}
+
+	before := time.Time{}
+	beforeParam := (*params)["before"]
+	if beforeParam != "" {
+		before, err = expr.ParseTime(beforeParam)
+		if err != nil {
+			return fmt.Errorf("invalid before parameter : %v", err)
+		}
+	}
+
+	if !after.IsZero() && !before.IsZero() && !after.Before(before) {
+		return fmt.Errorf("after must be earlier than before")
+	}
+
+	if maxAge == 0 && minAge == 0 && after.IsZero() && before.IsZero() {
+		return fmt.Errorf("at least one of the max_age, min_age, after " +
+			"or before parameters is required")
+	}
+
+	missingDate := (*params)["missing"]
+	switch missingDate {
+	case "":
+		missingDate = missingDateKeep
+	case missingDateKeep, missingDateDrop, missingDateNow:
+	default:
+		return fmt.Errorf("invalid missing parameter %q (must be keep, "+
+			"drop or now)", missingDate)
+	}
+
+	m.maxAge = maxAge
+	m.minAge = minAge
+	m.after = after
+	m.before = before
+	m.missingDate = missingDate
+
+	m.SetReady(true)
+
+	return nil
+}
+
+func (m *AgeProcessorModule) Parameters() *base_modules.ParameterMap {
+	return &base_modules.ParameterMap{
+		"max_age": "",
+		"min_age": "",
+		"after":   "",
+		"before":  "",
+		"missing": missingDateKeep,
+	}
+}
+
+func (m *AgeProcessorModule) Duplicate(specificId string) (base_modules.Module, error) {
+	duplicate := NewAgeProcessorModule(specificId)
+	err := pipeliner_modules.RegisterPipelinerProcessorModule(duplicate)
+	if err != nil {
+		return nil, err
+	}
+
+	return duplicate, nil
+}
+
+func (m *AgeProcessorModule) filterItem(
+	item *datatypes.PipelineItem) (bool, string) {
+	now := time.Now()
+
+	date := item.GetDate()
+	if !item.HasDate() {
+		switch m.missingDate {
+		case missingDateKeep:
+			return false, ""
+		case missingDateDrop:
+			return true, "item has no date"
+		}
+		date = now
+	}
+
+	age := now.Sub(date)
+
+	switch {
+	case m.maxAge != 0 && age > m.maxAge:
+		return true, fmt.Sprintf("age %s is more than %s",
+			age.Round(time.Second), m.maxAge)
+	case m.minAge != 0 && age < m.minAge:
+		return true, fmt.Sprintf("age %s is less than %s",
+			age.Round(time.Second), m.minAge)
+	case !m.after.IsZero() && !date.After(m.after):
+		return true, fmt.Sprintf("date %s is not after %s",
+			date.Format(time.RFC3339), m.after.Format(time.RFC3339))
+	case !m.before.IsZero() && !date.Before(m.before):
+		return true, fmt.Sprintf("date %s is not before %s",
+			date.Format(time.RFC3339), m.before.Format(time.RFC3339))
+	}
+
+	return false, ""
+}
+
+func init() {
+	pipeliner_modules.RegisterPipelinerProcessorModule(
+		NewAgeProcessorModule(""))
+}
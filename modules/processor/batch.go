@@ -0,0 +1,222 @@
+package input
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+
+	pipeliner_modules "github.com/brunoga/go-pipeliner/modules"
+	base_modules "gopkg.in/brunoga/go-modules.v1"
+)
+
+// BatchProcessorModule groups items by count, by time window or both and
+// emits a single batch item (see datatypes.NewBatchPipelineItem) for each
+// group.
+//
+// Without a slide interval, windows are tumbling: a batch is emitted whenever
+// count items were received or the window expires, whichever happens first.
+// With a slide interval, windows are sliding: every slide interval a batch
+// with the items received during the last window is emitted (if any new items
+// arrived since the last batch). In this case count, if set, limits batches to
+// the most recent items.
+type BatchProcessorModule struct {
+	*pipeliner_modules.GenericBufferedProcessorModule
+
+	count  int
+	window time.Duration
+	slide  time.Duration
+
+	items    []*bufferedItem
+	newItems bool
+}
+
+type bufferedItem struct {
+	item     *datatypes.PipelineItem
+	received time.Time
+	batches  int
+}
+
+func NewBatchProcessorModule(specificId string) *BatchProcessorModule {
+	batchProcessorModule := &BatchProcessorModule{
+		pipeliner_modules.NewGenericBufferedProcessorModule(
+			"Batch Processor Module", "1.0.0", "batch", specificId,
+			nil, nil),
+		0,
+		0,
+		0,
+		nil,
+		false,
+	}
+	batchProcessorModule.SetBufferFunc(batchProcessorModule.bufferItem)
+	batchProcessorModule.SetFlushFunc(batchProcessorModule.flushItems)
+
+	return batchProcessorModule
+}
+
+func (m *BatchProcessorModule) Configure(params *base_modules.ParameterMap) error {
+	countParam, ok := (*params)["count"]
+	if ok && countParam != "" {
+		count, err := strconv.Atoi(countParam)
+		if err != nil || count < 0 {
+			return fmt.Errorf("invalid count parameter %q", countParam)
+		}
+
+		m.count = count
+	}
+
+	windowParam, ok := (*params)["window"]
+	if ok && windowParam != "" {
+		window, err := time.ParseDuration(windowParam)
+		if err != nil || window < 0 {
+			return fmt.Errorf("invalid window parameter %q", windowParam)
+		}
+
+		m.window = window
+	}
+
+	slideParam, ok := (*params)["slide"]
+	if ok && slideParam != "" {
+		slide, err := time.ParseDuration(slideParam)
+		if err != nil || slide < 0 {
+			return fmt.Errorf("invalid slide parameter %q", slideParam)
+		}
+
+		m.slide = slide
+	}
+
+	if m.count == 0 && m.window == 0 {
+		return fmt.Errorf("at least one of count or window parameters " +
+			"must be set")
+	}
+
+	if m.slide > 0 {
+		if m.window == 0 {
+			return fmt.Errorf("window parameter is required for " +
+				"sliding windows")
+		}
+
+		if m.slide > m.window {
+			return fmt.Errorf("slide parameter must not be greater " +
+				"than window parameter")
+		}
+
+		m.SetFlushInterval(m.slide)
+	} else {
+		m.SetFlushInterval(m.window)
+	}
+
+	m.SetReady(true)
+
+	return nil
+}
+
+func (m *BatchProcessorModule) Parameters() *base_modules.ParameterMap {
+	return &base_modules.ParameterMap{
+		"count":  "0",
+		"window": "0s",
+		"slide":  "0s",
+	}
+}
+
+func (m *BatchProcessorModule) Duplicate(specificId string) (base_modules.Module, error) {
+	duplicate := NewBatchProcessorModule(specificId)
+	err := pipeliner_modules.RegisterPipelinerProcessorModule(duplicate)
+	if err != nil {
+		return nil, err
+	}
+
+	return duplicate, nil
+}
+
+func (m *BatchProcessorModule) bufferItem(
+	item *datatypes.PipelineItem) []*datatypes.PipelineItem {
+	m.items = append(m.items, &bufferedItem{item, time.Now(), 0})
+	m.newItems = true
+
+	if m.slide > 0 {
+		if m.count > 0 && len(m.items) > m.count {
+			m.dropOldest()
+		}
+
+		return nil
+	}
+
+	if m.count > 0 && len(m.items) >= m.count {
+		return m.emitBatch()
+	}
+
+	return nil
+}
+
+func (m *BatchProcessorModule) flushItems(bool) []*datatypes.PipelineItem {
+	if m.slide > 0 {
+		// Drop items that are out of the window.
+		windowStart := time.Now().Add(-m.window)
+		for len(m.items) > 0 && m.items[0].received.Before(windowStart) {
+			m.dropOldest()
+		}
+
+		if !m.newItems || len(m.items) == 0 {
+			return nil
+		}
+
+		m.newItems = false
+
+		return []*datatypes.PipelineItem{m.batchItem()}
+	}
+
+	if len(m.items) == 0 {
+		return nil
+	}
+
+	return m.emitBatch()
+}
+
+func (m *BatchProcessorModule) emitBatch() []*datatypes.PipelineItem {
+	batchItem := m.batchItem()
+
+	m.items = nil
+	m.newItems = false
+
+	return []*datatypes.PipelineItem{batchItem}
+}
+
+// dropOldest removes the oldest item from a sliding window. Items that were
+// never part of a batch are acknowledged as they are done.
+func (m *BatchProcessorModule) dropOldest() {
+	if m.items[0].batches == 0 {
+		m.Drop(m.items[0].item, "left the window before being batched")
+	}
+
+	m.items = m.items[1:]
+}
+
+func (m *BatchProcessorModule) batchItem() *datatypes.PipelineItem {
+	items := make([]*datatypes.PipelineItem, len(m.items))
+	for i, bufferedItem := range m.items {
+		items[i] = bufferedItem.item
+
+		// With sliding windows, items can be part of multiple batches
+		// and must be acknowledged by all of them. Items that were already
+		// acknowledged by an earlier batch stay acknowledged.
+		if bufferedItem.batches > 0 {
+			bufferedItem.item.AddPendingAcks(1)
+		}
+		bufferedItem.batches++
+	}
+
+	batchItem := datatypes.NewBatchPipelineItem(m.GenericId(), items)
+	for _, item := range items {
+		m.AddHistory(item, datatypes.ActionPassed,
+			"batched into "+batchItem.GetId())
+	}
+
+	return batchItem
+}
+
+func init() {
+	pipeliner_modules.RegisterPipelinerProcessorModule(
+		NewBatchProcessorModule(""))
+}
@@ -0,0 +1,182 @@
+package input
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+	"github.com/brunoga/go-pipeliner/expr"
+
+	pipeliner_modules "github.com/brunoga/go-pipeliner/modules"
+	base_modules "gopkg.in/brunoga/go-modules.v1"
+)
+
+// Metadata keys set by the episode processor.
+const (
+	// MetadataSeries is the series title (string).
+	MetadataSeries = "series"
+
+	// MetadataSeason is the season number (int64).
+	MetadataSeason = "season"
+
+	// MetadataEpisode is the episode number (int64). It is not set for
+	// season packs and date-based episodes.
+	MetadataEpisode = "episode"
+
+	// MetadataEndEpisode is the last episode number for multi-episode
+	// releases (int64).
+	MetadataEndEpisode = "end_episode"
+
+	// MetadataEpisodeDate is the air date for date-based episodes
+	// (time.Time).
+	MetadataEpisodeDate = "episode_date"
+
+	// MetadataEpisodeId identifies the episode within the series, like
+	// "S01E02", "S01E02-E03", "S01" (season packs) or "2024-01-31"
+	// (date-based episodes) (string).
+	MetadataEpisodeId = "episode_id"
+
+	// MetadataResolution is the video resolution, like "720p" (string).
+	MetadataResolution = "resolution"
+
+	// MetadataSource is the release source, like "WEB-DL" or "HDTV"
+	// (string).
+	MetadataSource = "source"
+
+	// MetadataCodec is the video codec, like "x264" or "H.265" (string).
+	MetadataCodec = "codec"
+
+	// MetadataGroup is the release group (string).
+	MetadataGroup = "group"
+
+	// MetadataProper is true for proper releases (bool).
+	MetadataProper = "proper"
+
+	// MetadataRepack is true for repacks (bool).
+	MetadataRepack = "repack"
+)
+
+// EpisodeProcessorModule parses TV episode release names (for example,
+// "Some.Show.S01E02.720p.WEB-DL.x264-GROUP") and stores the information found
+// as item metadata. Items with names that can't be parsed are kept or dropped
+// depending on the unmatched parameter.
+type EpisodeProcessorModule struct {
+	*pipeliner_modules.GenericProcessorModule
+
+	field         *expr.Field
+	dropUnmatched bool
+}
+
+func NewEpisodeProcessorModule(specificId string) *EpisodeProcessorModule {
+	episodeProcessorModule := &EpisodeProcessorModule{
+		pipeliner_modules.NewGenericProcessorModule(
+			"Episode Processor Module", "1.0.0", "episode",
+			specificId, nil),
+		nil,
+		false,
+	}
+	episodeProcessorModule.SetProcessorFunc(
+		episodeProcessorModule.parseItem)
+
+	return episodeProcessorModule
+}
+
+func (m *EpisodeProcessorModule) Configure(params *base_modules.ParameterMap) error {
+	fieldParam := (*params)["field"]
+	if fieldParam == "" {
+		fieldParam = "name"
+	}
+
+	field, err := expr.CompileField(fieldParam)
+	if err != nil {
+		return fmt.Errorf("invalid field parameter : %v", err)
+	}
+
+	dropUnmatched := false
+	switch unmatchedParam := (*params)["unmatched"]; unmatchedParam {
+	case "", "keep":
+	case "drop":
+		dropUnmatched = true
+	default:
+		return fmt.Errorf("invalid unmatched parameter %q (must be keep "+
+			"or drop)", unmatchedParam)
+	}
+
+	m.field = field
+	m.dropUnmatched = dropUnmatched
+
+	m.SetReady(true)
+
+	return nil
+}
+
+func (m *EpisodeProcessorModule) Parameters() *base_modules.ParameterMap {
+	return &base_modules.ParameterMap{
+		"field":     "name",
+		"unmatched": "keep",
+	}
+}
+
+func (m *EpisodeProcessorModule) Duplicate(specificId string) (base_modules.Module, error) {
+	duplicate := NewEpisodeProcessorModule(specificId)
+	err := pipeliner_modules.RegisterPipelinerProcessorModule(duplicate)
+	if err != nil {
+		return nil, err
+	}
+
+	return duplicate, nil
+}
+
+func (m *EpisodeProcessorModule) parseItem(
+	item *datatypes.PipelineItem) (bool, string) {
+	value := m.field.Value(item)
+	if value == nil {
+		return m.dropUnmatched, fmt.Sprintf("%s is not set", m.field)
+	}
+
+	info, ok := parseReleaseName(expr.ToString(value))
+	if !ok {
+		return m.dropUnmatched, fmt.Sprintf("%s is not an episode "+
+			"release name", m.field)
+	}
+
+	metadata := datatypes.Metadata{
+		MetadataSeries:    info.series,
+		MetadataSeason:    int64(info.season),
+		MetadataEpisodeId: info.episodeId(),
+		MetadataProper:    info.proper,
+		MetadataRepack:    info.repack,
+	}
+
+	if info.episode != 0 {
+		metadata[MetadataEpisode] = int64(info.episode)
+	}
+	if info.endEpisode != 0 {
+		metadata[MetadataEndEpisode] = int64(info.endEpisode)
+	}
+	if !info.date.IsZero() {
+		metadata[MetadataEpisodeDate] = info.date
+	}
+
+	for key, value := range map[string]string{
+		MetadataResolution: info.resolution,
+		MetadataSource:     info.source,
+		MetadataCodec:      info.codec,
+		MetadataGroup:      info.group,
+	} {
+		if value != "" {
+			metadata[key] = value
+		}
+	}
+
+	item.MergeMetadata(metadata)
+
+	return false, ""
+}
+
+func init() {
+	datatypes.RegisterMetadataType(MetadataEpisodeDate, time.Time{})
+
+	pipeliner_modules.RegisterPipelinerProcessorModule(
+		NewEpisodeProcessorModule(""))
+}
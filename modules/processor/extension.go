@@ -2,6 +2,10 @@ package input
 
 import (
 	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
 	"strings"
 
 	"github.com/brunoga/go-pipeliner/datatypes"
@@ -10,10 +14,26 @@ import (
 	base_modules "gopkg.in/brunoga/go-modules.v1"
 )
 
+// sniffLength is the number of bytes read from local files to detect their
+// content type.
+const sniffLength = 512
+
+// ExtensionProcessorModule filters items by the extension of their URLs or
+// name (case-insensitive if ignore_case is set). An item matches if any of its
+// URLs (or its name) ends with any of the configured extensions. In include
+// mode (the default) only matching items are let through. In exclude mode,
+// matching items are dropped. If sniffing is enabled, local file:// URLs also
+// match if their detected content type is the one associated with any of the
+// extensions.
 type ExtensionProcessorModule struct {
 	*pipeliner_modules.GenericProcessorModule
 
-	extension string
+	extensions   []string
+	contentTypes map[string]string
+	checkName    bool
+	exclude      bool
+	sniff        bool
+	ignoreCase   bool
 }
 
 func NewExtensionProcessorModule(specificId string) *ExtensionProcessorModule {
@@ -21,7 +41,12 @@ func NewExtensionProcessorModule(specificId string) *ExtensionProcessorModule {
 		pipeliner_modules.NewGenericProcessorModule(
 			"Extension Processor Module", "1.0.0", "extension",
 			specificId, nil),
-		"",
+		nil,
+		nil,
+		false,
+		false,
+		false,
+		false,
 	}
 	extensionProcessorModule.SetProcessorFunc(
 		extensionProcessorModule.filterExtension)
@@ -31,15 +56,78 @@ func NewExtensionProcessorModule(specificId string) *ExtensionProcessorModule {
 
 func (m *ExtensionProcessorModule) Configure(params *base_modules.ParameterMap) error {
 	extensionParam, ok := (*params)["extension"]
-	if !ok || extensionParam == "" {
+	if !ok || strings.TrimSpace(extensionParam) == "" {
 		return fmt.Errorf("required extension parameter not found")
 	}
 
-	if !strings.HasPrefix(extensionParam, ".") {
-		return fmt.Errorf("extension parameter must start with a dot (.)")
+	var extensions []string
+	contentTypes := make(map[string]string)
+	for _, extension := range strings.Split(extensionParam, "\n") {
+		extension = strings.TrimSpace(extension)
+		if extension == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(extension, ".") {
+			return fmt.Errorf("extension parameter must start with a "+
+				"dot (.) : %q", extension)
+		}
+
+		extensions = append(extensions, extension)
+
+		contentType := mediaType(mime.TypeByExtension(extension))
+		if contentType != "" {
+			contentTypes[contentType] = extension
+		}
+	}
+
+	checkName := false
+	switch checkParam := (*params)["check"]; checkParam {
+	case "", "urls":
+	case "name":
+		checkName = true
+	default:
+		return fmt.Errorf("invalid check parameter %q (must be urls or "+
+			"name)", checkParam)
+	}
+
+	exclude := false
+	switch modeParam := (*params)["mode"]; modeParam {
+	case "", "include":
+	case "exclude":
+		exclude = true
+	default:
+		return fmt.Errorf("invalid mode parameter %q (must be include or "+
+			"exclude)", modeParam)
+	}
+
+	sniff := false
+	sniffParam := (*params)["sniff"]
+	if sniffParam != "" {
+		var err error
+		sniff, err = strconv.ParseBool(sniffParam)
+		if err != nil {
+			return fmt.Errorf("invalid sniff parameter %q", sniffParam)
+		}
+	}
+
+	ignoreCase := false
+	ignoreCaseParam := (*params)["ignore_case"]
+	if ignoreCaseParam != "" {
+		var err error
+		ignoreCase, err = strconv.ParseBool(ignoreCaseParam)
+		if err != nil {
+			return fmt.Errorf("invalid ignore_case parameter %q",
+				ignoreCaseParam)
+		}
 	}
 
-	m.extension = extensionParam
+	m.extensions = extensions
+	m.contentTypes = contentTypes
+	m.checkName = checkName
+	m.exclude = exclude
+	m.sniff = sniff
+	m.ignoreCase = ignoreCase
 
 	m.SetReady(true)
 
@@ -48,7 +136,11 @@ func (m *ExtensionProcessorModule) Configure(params *base_modules.ParameterMap)
 
 func (m *ExtensionProcessorModule) Parameters() *base_modules.ParameterMap {
 	return &base_modules.ParameterMap{
-		"extension": "",
+		"extension":   "",
+		"check":       "urls",
+		"mode":        "include",
+		"sniff":       "false",
+		"ignore_case": "false",
 	}
 }
 
@@ -63,18 +155,121 @@ func (m *ExtensionProcessorModule) Duplicate(specificId string) (base_modules.Mo
 }
 
 func (m *ExtensionProcessorModule) filterExtension(
-	item *datatypes.PipelineItem) bool {
-	checkedUrl, err := item.GetUrl(0)
+	item *datatypes.PipelineItem) (bool, string) {
+	matched, description := m.matchItem(item)
+
+	if m.exclude && matched {
+		return true, fmt.Sprintf("%s (excluded)", description)
+	}
+
+	if !m.exclude && !matched {
+		return true, description
+	}
+
+	return false, ""
+}
+
+// matchItem returns true and a description of the match if the item matches
+// any of the extensions or false and a description of why it did not match
+// otherwise.
+func (m *ExtensionProcessorModule) matchItem(
+	item *datatypes.PipelineItem) (bool, string) {
+	if m.checkName {
+		extension := m.matchExtension(item.GetName())
+		if extension != "" {
+			return true, fmt.Sprintf("name %q has extension %q",
+				item.GetName(), extension)
+		}
+
+		return false, fmt.Sprintf("name %q does not have any of the "+
+			"extensions %v", item.GetName(), m.extensions)
+	}
+
+	itemUrls := item.GetUrls()
+	if len(itemUrls) == 0 {
+		return false, "item has no URLs"
+	}
+
+	for _, itemUrl := range itemUrls {
+		extension := m.matchExtension(itemUrl.Path)
+		if extension != "" {
+			return true, fmt.Sprintf("%q has extension %q",
+				itemUrl.Path, extension)
+		}
+
+		if !m.sniff || itemUrl.Scheme != "file" {
+			continue
+		}
+
+		contentType, err := sniffContentType(itemUrl.Path)
+		if err != nil {
+			m.Log(err)
+			continue
+		}
+
+		if _, ok := item.GetContentType(); !ok {
+			item.SetContentType(contentType)
+		}
+
+		extension, ok := m.contentTypes[mediaType(contentType)]
+		if ok {
+			return true, fmt.Sprintf("%q has content type %q (%s)",
+				itemUrl.Path, contentType, extension)
+		}
+	}
+
+	return false, fmt.Sprintf("no URL has any of the extensions %v",
+		m.extensions)
+}
+
+// matchExtension returns the extension the given path ends with or an empty
+// string if it does not end with any of them.
+func (m *ExtensionProcessorModule) matchExtension(itemPath string) string {
+	if m.ignoreCase {
+		itemPath = strings.ToLower(itemPath)
+	}
+
+	for _, extension := range m.extensions {
+		suffix := extension
+		if m.ignoreCase {
+			suffix = strings.ToLower(extension)
+		}
+
+		if strings.HasSuffix(itemPath, suffix) {
+			return extension
+		}
+	}
+
+	return ""
+}
+
+// sniffContentType detects the content type of the file at the given path
+// from its first bytes.
+func sniffContentType(filePath string) (string, error) {
+	file, err := os.Open(filePath)
 	if err != nil {
-		// TODO(bga): Log error.
-		return true
+		return "", err
 	}
+	defer file.Close()
 
-	if !strings.HasSuffix(checkedUrl.Path, m.extension) {
-		return true
+	buffer := make([]byte, sniffLength)
+	n, err := file.Read(buffer)
+	if err != nil && n == 0 {
+		return "", fmt.Errorf("error reading %q : %v", filePath, err)
+	}
+
+	return http.DetectContentType(buffer[:n]), nil
+}
+
+// mediaType returns the media type (without parameters) of the given content
+// type or an empty string if it is not valid.
+func mediaType(contentType string) string {
+	parsed, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
 	}
 
-	return false
+	return parsed
 }
 
 func init() {
@@ -0,0 +1,438 @@
+package input
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+	"github.com/brunoga/go-pipeliner/expr"
+	"github.com/brunoga/go-pipeliner/retry"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+
+	pipeliner_modules "github.com/brunoga/go-pipeliner/modules"
+	base_modules "gopkg.in/brunoga/go-modules.v1"
+)
+
+// FetchPayloadId is the id of the payload added by the fetch processor to the
+// items it fetches. The payload is a *FetchResult.
+const FetchPayloadId = "fetch"
+
+// Metadata keys set by the fetch processor (in addition to the well-known
+// content type and size).
+const (
+	// MetadataHttpStatus is the HTTP status code of the response (int64).
+	MetadataHttpStatus = "http_status"
+
+	// MetadataPageTitle is the title of the fetched HTML page (string).
+	MetadataPageTitle = "page_title"
+)
+
+// Ways to extract information from fetched HTML pages.
+const (
+	fetchExtractNone  = "none"
+	fetchExtractTitle = "title"
+	fetchExtractText  = "text"
+	fetchExtractAll   = "all"
+)
+
+// FetchResult is the result of fetching the URL of an item.
+type FetchResult struct {
+	Url         string      `json:"url"`
+	StatusCode  int         `json:"status_code"`
+	Header      http.Header `json:"header"`
+	ContentType string      `json:"content_type"`
+	Size        int64       `json:"size"`
+	Body        []byte      `json:"body,omitempty"`
+	Truncated   bool        `json:"truncated,omitempty"`
+	Title       string      `json:"title,omitempty"`
+	Text        string      `json:"text,omitempty"`
+	FetchedAt   time.Time   `json:"fetched_at"`
+}
+
+type fetchCacheEntry struct {
+	result  *FetchResult
+	expires time.Time
+}
+
+// fetchCall is a fetch in progress. Concurrent fetches of the same URL wait
+// for it instead of fetching the URL again.
+type fetchCall struct {
+	done   chan struct{}
+	result *FetchResult
+	err    error
+}
+
+// FetchProcessorModule fetches the first HTTP(S) URL of each item and adds a
+// FetchResult payload with the response status, headers, content type, size
+// and (optionally) body. The content type, size, HTTP status and page title are
+// also added as metadata and the readable text of HTML pages can be used as
+// the item description. Error responses (like 404 or 503) are recorded as
+// well, only failures to fetch the URL at all are handled according to
+// on_error. Responses are cached for cache_ttl and concurrent fetches of the
+// same URL are done only once. Use the workers parameter to fetch several items
+// concurrently.
+type FetchProcessorModule struct {
+	*pipeliner_modules.GenericProcessorModule
+
+	client         *http.Client
+	userAgent      string
+	maxBody        int64
+	storeBody      bool
+	extract        string
+	setDescription bool
+	dropOnError    bool
+	cacheTtl       time.Duration
+
+	cacheMutex sync.Mutex
+	cache      map[string]*fetchCacheEntry
+	cacheSwept time.Time
+	inFlight   map[string]*fetchCall
+}
+
+func NewFetchProcessorModule(specificId string) *FetchProcessorModule {
+	fetchProcessorModule := &FetchProcessorModule{
+		pipeliner_modules.NewGenericProcessorModule(
+			"Fetch Processor Module", "1.0.0", "fetch",
+			specificId, nil),
+		nil,
+		"",
+		0,
+		false,
+		fetchExtractTitle,
+		false,
+		false,
+		0,
+		sync.Mutex{},
+		make(map[string]*fetchCacheEntry),
+		time.Time{},
+		make(map[string]*fetchCall),
+	}
+	fetchProcessorModule.SetProcessorFunc(fetchProcessorModule.fetchItem)
+
+	return fetchProcessorModule
+}
+
+func (m *FetchProcessorModule) Configure(params *base_modules.ParameterMap) error {
+	timeoutParam := (*params)["timeout"]
+	timeout, err := expr.ParseDuration(timeoutParam)
+	if err != nil || timeout <= 0 {
+		return fmt.Errorf("invalid timeout parameter %q", timeoutParam)
+	}
+
+	maxBodyParam := (*params)["max_body"]
+	maxBody, err := expr.ParseSize(maxBodyParam)
+	if err != nil || maxBody <= 0 {
+		return fmt.Errorf("invalid max_body parameter %q", maxBodyParam)
+	}
+
+	storeBodyParam := (*params)["store_body"]
+	storeBody, err := strconv.ParseBool(storeBodyParam)
+	if err != nil {
+		return fmt.Errorf("invalid store_body parameter %q", storeBodyParam)
+	}
+
+	extract := (*params)["extract"]
+	switch extract {
+	case fetchExtractNone, fetchExtractTitle, fetchExtractText,
+		fetchExtractAll:
+	default:
+		return fmt.Errorf("invalid extract parameter %q (must be none, "+
+			"title, text or all)", extract)
+	}
+
+	setDescriptionParam := (*params)["set_description"]
+	setDescription, err := strconv.ParseBool(setDescriptionParam)
+	if err != nil {
+		return fmt.Errorf("invalid set_description parameter %q",
+			setDescriptionParam)
+	}
+	if setDescription && extract != fetchExtractText &&
+		extract != fetchExtractAll {
+		return fmt.Errorf("set_description requires extract to be text " +
+			"or all")
+	}
+
+	cacheTtl := time.Duration(0)
+	cacheTtlParam := (*params)["cache_ttl"]
+	if cacheTtlParam != "0" {
+		cacheTtl, err = expr.ParseDuration(cacheTtlParam)
+		if err != nil || cacheTtl < 0 {
+			return fmt.Errorf("invalid cache_ttl parameter %q",
+				cacheTtlParam)
+		}
+	}
+
+	maxPerHostParam := (*params)["max_per_host"]
+	maxPerHost, err := strconv.Atoi(maxPerHostParam)
+	if err != nil || maxPerHost < 0 {
+		return fmt.Errorf("invalid max_per_host parameter %q",
+			maxPerHostParam)
+	}
+
+	dropOnError := false
+	switch onErrorParam := (*params)["on_error"]; onErrorParam {
+	case "keep":
+	case "drop":
+		dropOnError = true
+	default:
+		return fmt.Errorf("invalid on_error parameter %q (must be keep "+
+			"or drop)", onErrorParam)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxConnsPerHost = maxPerHost
+
+	m.client = &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}
+	m.userAgent = (*params)["user_agent"]
+	m.maxBody = maxBody
+	m.storeBody = storeBody
+	m.extract = extract
+	m.setDescription = setDescription
+	m.dropOnError = dropOnError
+	m.cacheTtl = cacheTtl
+
+	m.SetReady(true)
+
+	return nil
+}
+
+func (m *FetchProcessorModule) Parameters() *base_modules.ParameterMap {
+	return &base_modules.ParameterMap{
+		"timeout":         "30s",
+		"max_body":        "1MiB",
+		"store_body":      "false",
+		"extract":         fetchExtractTitle,
+		"set_description": "false",
+		"cache_ttl":       "1h",
+		"max_per_host":    "2",
+		"user_agent":      "go-pipeliner",
+		"on_error":        "keep",
+	}
+}
+
+func (m *FetchProcessorModule) Duplicate(specificId string) (base_modules.Module, error) {
+	duplicate := NewFetchProcessorModule(specificId)
+	err := pipeliner_modules.RegisterPipelinerProcessorModule(duplicate)
+	if err != nil {
+		return nil, err
+	}
+
+	return duplicate, nil
+}
+
+func (m *FetchProcessorModule) fetchItem(
+	item *datatypes.PipelineItem) (bool, string) {
+	var fetchUrl *url.URL
+	for _, itemUrl := range item.GetUrls() {
+		if itemUrl.Scheme == "http" || itemUrl.Scheme == "https" {
+			fetchUrl = itemUrl
+			break
+		}
+	}
+	if fetchUrl == nil {
+		// Nothing to fetch.
+		return false, ""
+	}
+
+	result, err := m.cachedFetch(fetchUrl.String())
+	if err != nil {
+		err = fmt.Errorf("error fetching %q : %v", fetchUrl, err)
+		if m.dropOnError {
+			m.DeadLetter(item, err)
+			return true, err.Error()
+		}
+
+		m.Log(err)
+		return false, ""
+	}
+
+	item.ReplacePayload(FetchPayloadId, result)
+
+	item.SetMetadata(MetadataHttpStatus, int64(result.StatusCode))
+	if result.ContentType != "" {
+		item.SetContentType(result.ContentType)
+	}
+	if result.Size >= 0 {
+		item.SetSize(result.Size)
+	}
+	if result.Title != "" {
+		item.SetMetadata(MetadataPageTitle, result.Title)
+	}
+	if m.setDescription && result.Text != "" {
+		item.SetDescription(result.Text)
+	}
+
+	return false, ""
+}
+
+// cachedFetch returns the cached result for the given URL, if any, or fetches
+// it. If the URL is already being fetched, it waits for that fetch instead.
+func (m *FetchProcessorModule) cachedFetch(fetchUrl string) (*FetchResult,
+	error) {
+	m.cacheMutex.Lock()
+	if entry, ok := m.cache[fetchUrl]; ok && time.Now().Before(entry.expires) {
+		m.cacheMutex.Unlock()
+		return entry.result, nil
+	}
+	if call, ok := m.inFlight[fetchUrl]; ok {
+		m.cacheMutex.Unlock()
+		<-call.done
+		return call.result, call.err
+	}
+	call := &fetchCall{make(chan struct{}), nil, nil}
+	m.inFlight[fetchUrl] = call
+	m.cacheMutex.Unlock()
+
+	call.result, call.err = m.retryFetch(fetchUrl)
+
+	m.cacheMutex.Lock()
+	delete(m.inFlight, fetchUrl)
+	if m.cacheTtl > 0 && call.err == nil &&
+		!transientStatus(call.result.StatusCode) {
+		now := time.Now()
+		m.sweepCache(now)
+		m.cache[fetchUrl] = &fetchCacheEntry{call.result,
+			now.Add(m.cacheTtl)}
+	}
+	m.cacheMutex.Unlock()
+
+	close(call.done)
+
+	return call.result, call.err
+}
+
+// sweepCache removes expired entries from the cache. It does nothing if the
+// cache was swept less than cacheTtl ago. Must be called with cacheMutex held.
+func (m *FetchProcessorModule) sweepCache(now time.Time) {
+	if now.Sub(m.cacheSwept) < m.cacheTtl {
+		return
+	}
+
+	for fetchUrl, entry := range m.cache {
+		if !now.Before(entry.expires) {
+			delete(m.cache, fetchUrl)
+		}
+	}
+
+	m.cacheSwept = now
+}
+
+// retryFetch fetches the given URL, retrying transient failures according to
+// the module retry policy. If the server still responds with a transient
+// error status after all retries, that response is returned.
+func (m *FetchProcessorModule) retryFetch(fetchUrl string) (*FetchResult,
+	error) {
+	var result *FetchResult
+	err := m.Retry(func() error {
+		var err error
+		result, err = m.fetch(fetchUrl)
+		return err
+	})
+	if err != nil && result == nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// fetch fetches the given URL. Responses with a transient error status are
+// returned together with a (retryable) error.
+func (m *FetchProcessorModule) fetch(fetchUrl string) (*FetchResult, error) {
+	request, err := http.NewRequest("GET", fetchUrl, nil)
+	if err != nil {
+		return nil, retry.Permanent(err)
+	}
+	request.Header.Set("User-Agent", m.userAgent)
+
+	response, err := m.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(response.Body, m.maxBody+1))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &FetchResult{
+		Url:         fetchUrl,
+		StatusCode:  response.StatusCode,
+		Header:      response.Header,
+		ContentType: response.Header.Get("Content-Type"),
+		Size:        int64(len(body)),
+		FetchedAt:   time.Now(),
+	}
+
+	if int64(len(body)) > m.maxBody {
+		body = body[:m.maxBody]
+		result.Truncated = true
+		result.Size = response.ContentLength
+	}
+
+	if result.ContentType == "" {
+		result.ContentType = http.DetectContentType(body)
+	}
+
+	if m.storeBody {
+		result.Body = body
+	}
+
+	if m.extract != fetchExtractNone &&
+		mediaType(result.ContentType) == "text/html" {
+		m.extractHtml(result, body)
+	}
+
+	if transientStatus(response.StatusCode) {
+		return result, fmt.Errorf("unexpected status %q", response.Status)
+	}
+
+	return result, nil
+}
+
+// transientStatus returns true for HTTP status codes that might go away when
+// retrying (server errors and throttling).
+func transientStatus(statusCode int) bool {
+	return statusCode >= 500 || statusCode == http.StatusTooManyRequests
+}
+
+// extractHtml sets the title and/or text of the given result from the given
+// HTML body.
+func (m *FetchProcessorModule) extractHtml(result *FetchResult, body []byte) {
+	reader, err := charset.NewReader(bytes.NewReader(body), result.ContentType)
+	if err != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	root, err := html.Parse(reader)
+	if err != nil {
+		return
+	}
+
+	if m.extract == fetchExtractTitle || m.extract == fetchExtractAll {
+		result.Title = htmlTitle(root)
+	}
+
+	if m.extract == fetchExtractText || m.extract == fetchExtractAll {
+		result.Text = htmlText(root, true)
+	}
+}
+
+func init() {
+	datatypes.RegisterPayloadCodec(FetchPayloadId,
+		datatypes.NewJSONPayloadCodec(&FetchResult{}))
+
+	pipeliner_modules.RegisterPipelinerProcessorModule(
+		NewFetchProcessorModule(""))
+}
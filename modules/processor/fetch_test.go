@@ -0,0 +1,200 @@
+package input
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+)
+
+func newTestFetchModule(t *testing.T,
+	params map[string]string) *FetchProcessorModule {
+	m := NewFetchProcessorModule("test")
+
+	parameters := m.Parameters()
+	for key, value := range params {
+		(*parameters)[key] = value
+	}
+
+	if err := m.Configure(parameters); err != nil {
+		t.Fatalf("Configure : %v", err)
+	}
+
+	m.RetryPolicy().MaxAttempts = 3
+	m.RetryPolicy().InitialBackoff = time.Millisecond
+
+	return m
+}
+
+func newTestFetchItem(fetchUrl string) *datatypes.PipelineItem {
+	item := datatypes.NewPipelineItem("test")
+	item.AddUrlString(fetchUrl)
+
+	return item
+}
+
+func TestFetchStatus(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			switch r.URL.Path {
+			case "/page":
+				w.Header().Set("Content-Type", "text/html")
+				w.Write([]byte("<html><head><title> A  page </title>" +
+					"</head><body>Text</body></html>"))
+			case "/gone":
+				http.Error(w, "gone", http.StatusGone)
+			case "/broken":
+				http.Error(w, "broken", http.StatusInternalServerError)
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+	defer server.Close()
+
+	m := newTestFetchModule(t, map[string]string{"on_error": "drop"})
+
+	tests := []struct {
+		path     string
+		status   int64
+		title    string
+		requests int32
+	}{
+		{"/page", http.StatusOK, "A page", 1},
+		{"/missing", http.StatusNotFound, "", 1},
+		{"/gone", http.StatusGone, "", 1},
+		// Server errors are retried.
+		{"/broken", http.StatusInternalServerError, "", 3},
+	}
+
+	for _, test := range tests {
+		atomic.StoreInt32(&requests, 0)
+
+		item := newTestFetchItem(server.URL + test.path)
+		filtered, reason := m.fetchItem(item)
+		if filtered {
+			t.Errorf("fetchItem(%q) : filtered (%s)", test.path, reason)
+			continue
+		}
+
+		status, _ := item.GetMetadata(MetadataHttpStatus)
+		if status != test.status {
+			t.Errorf("fetchItem(%q) : http_status = %v, expected %d",
+				test.path, status, test.status)
+		}
+
+		title, _ := item.GetMetadata(MetadataPageTitle)
+		if test.title != "" && title != test.title {
+			t.Errorf("fetchItem(%q) : page_title = %v, expected %q",
+				test.path, title, test.title)
+		}
+
+		if atomic.LoadInt32(&requests) != test.requests {
+			t.Errorf("fetchItem(%q) : %d requests, expected %d", test.path,
+				atomic.LoadInt32(&requests), test.requests)
+		}
+	}
+}
+
+func TestFetchTransportError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	serverUrl := server.URL
+	server.Close()
+
+	m := newTestFetchModule(t, map[string]string{"on_error": "drop"})
+
+	filtered, _ := m.fetchItem(newTestFetchItem(serverUrl))
+	if !filtered {
+		t.Errorf("fetchItem : not filtered on a transport error")
+	}
+}
+
+func TestFetchCache(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			if r.URL.Path == "/broken" {
+				http.Error(w, "broken", http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte("content"))
+		}))
+	defer server.Close()
+
+	m := newTestFetchModule(t, map[string]string{"cache_ttl": "1h"})
+	m.RetryPolicy().MaxAttempts = 1
+
+	for i := 0; i < 2; i++ {
+		m.fetchItem(newTestFetchItem(server.URL + "/page"))
+		m.fetchItem(newTestFetchItem(server.URL + "/broken"))
+	}
+
+	// Transient errors are not cached.
+	if atomic.LoadInt32(&requests) != 3 {
+		t.Errorf("%d requests, expected 3", atomic.LoadInt32(&requests))
+	}
+
+	m.cacheMutex.Lock()
+	defer m.cacheMutex.Unlock()
+
+	if len(m.cache) != 1 {
+		t.Fatalf("%d cache entries, expected 1", len(m.cache))
+	}
+
+	// Expired entries are removed when the cache is swept.
+	now := time.Now().Add(2 * time.Hour)
+	m.sweepCache(now)
+	if len(m.cache) != 0 {
+		t.Errorf("%d cache entries after sweep, expected 0", len(m.cache))
+	}
+}
+
+func TestFetchInFlight(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			<-release
+			w.Write([]byte("content"))
+		}))
+	defer server.Close()
+
+	// Even without a cache, concurrent fetches of the same URL are done
+	// only once.
+	m := newTestFetchModule(t, map[string]string{"cache_ttl": "0"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			item := newTestFetchItem(server.URL)
+			m.fetchItem(item)
+			if status, _ := item.GetMetadata(MetadataHttpStatus); status !=
+				int64(http.StatusOK) {
+				t.Errorf("http_status = %v, expected 200", status)
+			}
+		}()
+	}
+
+	// Wait for the first request to reach the server and for the other
+	// fetches to wait for it.
+	for atomic.LoadInt32(&requests) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	wg.Wait()
+
+	if atomic.LoadInt32(&requests) != 1 {
+		t.Errorf("%d requests, expected 1", atomic.LoadInt32(&requests))
+	}
+}
@@ -0,0 +1,69 @@
+package input
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// compareValues compares the given values and returns a negative number if a
+// is less than b, zero if they are equal and a positive number if a is
+// greater than b. Values of different types are compared by their string
+// representation.
+func compareValues(a, b interface{}) int {
+	aTime, aIsTime := asTime(a)
+	bTime, bIsTime := asTime(b)
+	if aIsTime && bIsTime {
+		switch {
+		case aTime.Before(bTime):
+			return -1
+		case aTime.After(bTime):
+			return 1
+		}
+		return 0
+	}
+
+	aNumber, aIsNumber := asNumber(a)
+	bNumber, bIsNumber := asNumber(b)
+	if aIsNumber && bIsNumber {
+		switch {
+		case aNumber < bNumber:
+			return -1
+		case aNumber > bNumber:
+			return 1
+		}
+		return 0
+	}
+
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func asTime(value interface{}) (time.Time, bool) {
+	switch typedValue := value.(type) {
+	case time.Time:
+		return typedValue, true
+	case *time.Time:
+		if typedValue != nil {
+			return *typedValue, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+func asNumber(value interface{}) (float64, bool) {
+	reflectValue := reflect.ValueOf(value)
+	switch reflectValue.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		return float64(reflectValue.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		return float64(reflectValue.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return reflectValue.Float(), true
+	}
+
+	return 0, false
+}
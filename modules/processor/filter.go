@@ -0,0 +1,86 @@
+package input
+
+import (
+	"fmt"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+	"github.com/brunoga/go-pipeliner/expr"
+
+	pipeliner_modules "github.com/brunoga/go-pipeliner/modules"
+	base_modules "gopkg.in/brunoga/go-modules.v1"
+)
+
+// FilterProcessorModule lets through only items for which the configured
+// expression is true. See the expr package for the expression syntax.
+type FilterProcessorModule struct {
+	*pipeliner_modules.GenericProcessorModule
+
+	expression *expr.Expression
+}
+
+func NewFilterProcessorModule(specificId string) *FilterProcessorModule {
+	filterProcessorModule := &FilterProcessorModule{
+		pipeliner_modules.NewGenericProcessorModule(
+			"Filter Processor Module", "1.0.0", "filter",
+			specificId, nil),
+		nil,
+	}
+	filterProcessorModule.SetProcessorFunc(
+		filterProcessorModule.filterItem)
+
+	return filterProcessorModule
+}
+
+func (m *FilterProcessorModule) Configure(params *base_modules.ParameterMap) error {
+	expressionParam, ok := (*params)["expression"]
+	if !ok || expressionParam == "" {
+		return fmt.Errorf("required expression parameter not found")
+	}
+
+	expression, err := expr.CompileCondition(expressionParam)
+	if err != nil {
+		return fmt.Errorf("invalid expression parameter : %v", err)
+	}
+
+	m.expression = expression
+
+	m.SetReady(true)
+
+	return nil
+}
+
+func (m *FilterProcessorModule) Parameters() *base_modules.ParameterMap {
+	return &base_modules.ParameterMap{
+		"expression": "",
+	}
+}
+
+func (m *FilterProcessorModule) Duplicate(specificId string) (base_modules.Module, error) {
+	duplicate := NewFilterProcessorModule(specificId)
+	err := pipeliner_modules.RegisterPipelinerProcessorModule(duplicate)
+	if err != nil {
+		return nil, err
+	}
+
+	return duplicate, nil
+}
+
+func (m *FilterProcessorModule) filterItem(
+	item *datatypes.PipelineItem) (bool, string) {
+	matched, err := m.expression.Match(item)
+	if err != nil {
+		m.Log(err)
+		return true, fmt.Sprintf("error evaluating expression : %v", err)
+	}
+
+	if !matched {
+		return true, fmt.Sprintf("expression is false : %s", m.expression)
+	}
+
+	return false, ""
+}
+
+func init() {
+	pipeliner_modules.RegisterPipelinerProcessorModule(
+		NewFilterProcessorModule(""))
+}
@@ -0,0 +1,453 @@
+package input
+
+import (
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// htmlSkippedElements are elements whose content is never rendered as text.
+var htmlSkippedElements = map[atom.Atom]bool{
+	atom.Head:     true,
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Iframe:   true,
+	atom.Object:   true,
+	atom.Svg:      true,
+}
+
+// htmlBoilerplateElements are elements that usually do not contain the main
+// content of a page.
+var htmlBoilerplateElements = map[atom.Atom]bool{
+	atom.Nav:    true,
+	atom.Header: true,
+	atom.Footer: true,
+	atom.Aside:  true,
+	atom.Form:   true,
+	atom.Button: true,
+}
+
+// htmlBlockElements are elements that start a new line of text.
+var htmlBlockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Blockquote: true,
+	atom.Br: true, atom.Dd: true, atom.Div: true, atom.Dl: true,
+	atom.Dt: true, atom.Figcaption: true, atom.Figure: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true,
+	atom.H5: true, atom.H6: true, atom.Hr: true, atom.Li: true,
+	atom.Main: true, atom.Ol: true, atom.P: true, atom.Pre: true,
+	atom.Section: true, atom.Table: true, atom.Tr: true, atom.Ul: true,
+}
+
+// htmlTitle returns the content of the first title element in the given
+// document or an empty string if there is none.
+func htmlTitle(root *html.Node) string {
+	title := findHtmlElement(root, atom.Title)
+	if title == nil {
+		return ""
+	}
+
+	return collapseSpaces(htmlNodeText(title, false))
+}
+
+// htmlText returns the text in the given document (or fragment), with one
+// line per block element. If readable is
+// true, only the main content (the first article or main element, if any)
+// is considered and boilerplate elements like navigation are skipped.
+func htmlText(root *html.Node, readable bool) string {
+	if readable {
+		for _, element := range []atom.Atom{atom.Article, atom.Main} {
+			content := findHtmlElement(root, element)
+			if content != nil {
+				root = content
+				break
+			}
+		}
+	}
+
+	return normalizeTextLines(htmlNodeText(root, readable))
+}
+
+// parseHtmlFragment parses the given HTML fragment (for example, a feed item
+// description) and returns a node containing it.
+func parseHtmlFragment(fragment string) (*html.Node, error) {
+	body := &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	}
+
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), body)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, node := range nodes {
+		body.AppendChild(node)
+	}
+
+	return body, nil
+}
+
+func findHtmlElement(node *html.Node, element atom.Atom) *html.Node {
+	if node.Type == html.ElementNode && node.DataAtom == element {
+		return node
+	}
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		found := findHtmlElement(child, element)
+		if found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+func htmlNodeText(node *html.Node, skipBoilerplate bool) string {
+	var builder strings.Builder
+
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		switch node.Type {
+		case html.TextNode:
+			builder.WriteString(node.Data)
+			return
+		case html.ElementNode:
+			if htmlSkippedElements[node.DataAtom] ||
+				(skipBoilerplate && htmlBoilerplateElements[node.DataAtom]) {
+				return
+			}
+		}
+
+		block := node.Type == html.ElementNode &&
+			htmlBlockElements[node.DataAtom]
+		if block {
+			builder.WriteString("\n")
+		}
+
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+
+		if block {
+			builder.WriteString("\n")
+		}
+	}
+	walk(node)
+
+	return builder.String()
+}
+
+// normalizeTextLines collapses spaces in each line of the given text and
+// removes empty lines.
+func normalizeTextLines(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = collapseSpaces(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func collapseSpaces(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// htmlSanitizedAllowedElements are the elements kept by sanitizeHtml. Other
+// elements are replaced by their content, except for htmlSkippedElements and
+// htmlUnsafeElements, which are removed with their content.
+var htmlSanitizedAllowedElements = map[atom.Atom]bool{
+	atom.A: true, atom.B: true, atom.Blockquote: true, atom.Br: true,
+	atom.Code: true, atom.Dd: true, atom.Del: true, atom.Div: true,
+	atom.Dl: true, atom.Dt: true, atom.Em: true, atom.H1: true,
+	atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true,
+	atom.H6: true, atom.Hr: true, atom.I: true, atom.Img: true,
+	atom.Li: true, atom.Ol: true, atom.P: true, atom.Pre: true,
+	atom.S: true, atom.Span: true, atom.Strong: true, atom.Sub: true,
+	atom.Sup: true, atom.Table: true, atom.Tbody: true, atom.Td: true,
+	atom.Th: true, atom.Thead: true, atom.Tr: true, atom.U: true,
+	atom.Ul: true,
+}
+
+// htmlUnsafeElements are removed with their content by sanitizeHtml.
+var htmlUnsafeElements = map[atom.Atom]bool{
+	atom.Embed: true, atom.Form: true, atom.Frame: true, atom.Frameset: true,
+	atom.Link: true, atom.Meta: true, atom.Base: true, atom.Applet: true,
+	atom.Input: true, atom.Textarea: true, atom.Select: true,
+	atom.Button: true,
+}
+
+// htmlSanitizedAllowedAttributes are the attributes kept by sanitizeHtml for
+// each element.
+var htmlSanitizedAllowedAttributes = map[atom.Atom]map[string]bool{
+	atom.A: {"href": true, "title": true},
+	atom.Img: {"src": true, "alt": true, "title": true, "width": true,
+		"height": true},
+	atom.Td: {"colspan": true, "rowspan": true},
+	atom.Th: {"colspan": true, "rowspan": true},
+}
+
+// htmlTrackers are the hosts (including their subdomains) and path prefixes
+// of known tracking pixels. An empty path matches any path.
+var htmlTrackers = []struct {
+	host string
+	path string
+}{
+	{"feedburner.com", "/~r/"},
+	{"feedburner.com", "/~ff/"},
+	{"doubleclick.net", ""},
+	{"google-analytics.com", ""},
+	{"stats.wordpress.com", ""},
+	{"pixel.wp.com", ""},
+	{"pixel.quantserve.com", ""},
+	{"sb.scorecardresearch.com", ""},
+	{"feeds.feedblitz.com", "/~/i/"},
+}
+
+// sanitizeHtml returns a copy of the given node's children with only safe
+// elements and attributes. Scripts, styles, tracking pixels, event handlers,
+// inline styles and links with unsafe schemes are removed.
+func sanitizeHtml(root *html.Node) []*html.Node {
+	var sanitized []*html.Node
+	for child := root.FirstChild; child != nil; child = child.NextSibling {
+		sanitized = append(sanitized, sanitizeHtmlNode(child)...)
+	}
+
+	return sanitized
+}
+
+func sanitizeHtmlNode(node *html.Node) []*html.Node {
+	switch node.Type {
+	case html.TextNode:
+		return []*html.Node{{Type: html.TextNode, Data: node.Data}}
+	case html.ElementNode:
+	default:
+		// Comments, doctypes and so on.
+		return nil
+	}
+
+	if htmlSkippedElements[node.DataAtom] || htmlUnsafeElements[node.DataAtom] {
+		return nil
+	}
+
+	var children []*html.Node
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		children = append(children, sanitizeHtmlNode(child)...)
+	}
+
+	if !htmlSanitizedAllowedElements[node.DataAtom] {
+		// Keep the content only.
+		return children
+	}
+
+	sanitized := &html.Node{
+		Type:     html.ElementNode,
+		Data:     node.DataAtom.String(),
+		DataAtom: node.DataAtom,
+	}
+
+	allowedAttributes := htmlSanitizedAllowedAttributes[node.DataAtom]
+	for _, attribute := range node.Attr {
+		key := strings.ToLower(attribute.Key)
+		if attribute.Namespace != "" || !allowedAttributes[key] {
+			continue
+		}
+
+		if (key == "href" || key == "src") && !isSafeHtmlUrl(attribute.Val) {
+			continue
+		}
+
+		sanitized.Attr = append(sanitized.Attr, html.Attribute{
+			Key: key,
+			Val: attribute.Val,
+		})
+	}
+
+	switch node.DataAtom {
+	case atom.Img:
+		if isTrackingPixel(sanitized) {
+			return nil
+		}
+	case atom.A:
+		sanitized.Attr = append(sanitized.Attr, html.Attribute{
+			Key: "rel",
+			Val: "nofollow noopener noreferrer",
+		})
+	}
+
+	for _, child := range children {
+		sanitized.AppendChild(child)
+	}
+
+	return []*html.Node{sanitized}
+}
+
+// isSafeHtmlUrl returns true for relative URLs and URLs with the http, https
+// or mailto schemes.
+func isSafeHtmlUrl(value string) bool {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	colon := strings.IndexByte(value, ':')
+	if colon < 0 || strings.ContainsAny(value[:colon], "/?#") {
+		// Relative URL.
+		return true
+	}
+
+	switch value[:colon] {
+	case "http", "https", "mailto":
+		return true
+	}
+
+	return false
+}
+
+// isTrackingPixel returns true if the given (sanitized) image looks like a
+// tracking pixel: it has no source, a width or height of 0 or 1 pixels or a
+// source from a known tracker.
+func isTrackingPixel(img *html.Node) bool {
+	src := ""
+	for _, attribute := range img.Attr {
+		switch attribute.Key {
+		case "src":
+			src = strings.TrimSpace(attribute.Val)
+		case "width", "height":
+			value := strings.TrimSpace(strings.TrimSuffix(
+				strings.TrimSpace(attribute.Val), "px"))
+			if value == "0" || value == "1" {
+				return true
+			}
+		}
+	}
+
+	if src == "" {
+		return true
+	}
+
+	srcUrl, err := url.Parse(src)
+	if err != nil {
+		return false
+	}
+
+	host := strings.ToLower(srcUrl.Hostname())
+	for _, tracker := range htmlTrackers {
+		if host != tracker.host && !strings.HasSuffix(host, "."+tracker.host) {
+			continue
+		}
+
+		if strings.HasPrefix(srcUrl.Path, tracker.path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// truncateText truncates the given text to at most maxLength runes (including
+// the ellipsis), preferably at a word boundary. Text that is already short
+// enough is returned as is.
+func truncateText(text string, maxLength int, ellipsis string) string {
+	if maxLength <= 0 || utf8.RuneCountInString(text) <= maxLength {
+		return text
+	}
+
+	limit := maxLength - utf8.RuneCountInString(ellipsis)
+	if limit <= 0 {
+		return string([]rune(ellipsis)[:maxLength])
+	}
+
+	return cutText(text, limit) + ellipsis
+}
+
+// cutText returns at most limit runes from the start of the given text. If
+// possible, the text is cut at a word boundary close to the limit.
+func cutText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+
+	cut := limit
+	for i := limit; i > limit*4/5; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+
+	return strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace)
+}
+
+// truncateHtml truncates the given nodes so their text has at most maxLength
+// runes (including the ellipsis, which is added to the last text kept). It
+// returns the truncated nodes.
+func truncateHtml(nodes []*html.Node, maxLength int,
+	ellipsis string) []*html.Node {
+	length := 0
+	for _, node := range nodes {
+		length += utf8.RuneCountInString(htmlNodeText(node, false))
+	}
+	if maxLength <= 0 || length <= maxLength {
+		return nodes
+	}
+
+	remaining := maxLength - utf8.RuneCountInString(ellipsis)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	// truncate returns false once the limit was reached, after removing
+	// everything after the current node.
+	var truncate func(node *html.Node) bool
+	truncate = func(node *html.Node) bool {
+		if node.Type == html.TextNode {
+			length := utf8.RuneCountInString(node.Data)
+			if length <= remaining {
+				remaining -= length
+				return true
+			}
+			node.Data = cutText(node.Data, remaining) + ellipsis
+			return false
+		}
+
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if !truncate(child) {
+				for child.NextSibling != nil {
+					node.RemoveChild(child.NextSibling)
+				}
+				return false
+			}
+		}
+
+		return true
+	}
+
+	for i, node := range nodes {
+		if !truncate(node) {
+			return nodes[:i+1]
+		}
+	}
+
+	return nodes
+}
+
+// renderHtml renders the given nodes.
+func renderHtml(nodes []*html.Node) (string, error) {
+	var builder strings.Builder
+	for _, node := range nodes {
+		err := html.Render(&builder, node)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return builder.String(), nil
+}
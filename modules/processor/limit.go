@@ -0,0 +1,92 @@
+package input
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+
+	pipeliner_modules "github.com/brunoga/go-pipeliner/modules"
+	base_modules "gopkg.in/brunoga/go-modules.v1"
+)
+
+// LimitProcessorModule lets through only the first N items it receives and
+// drops all others. If a flush interval is set, the count is reset every time
+// it expires so up to N items are let through per interval.
+type LimitProcessorModule struct {
+	*pipeliner_modules.GenericBufferedProcessorModule
+
+	count int
+	seen  int
+}
+
+func NewLimitProcessorModule(specificId string) *LimitProcessorModule {
+	limitProcessorModule := &LimitProcessorModule{
+		pipeliner_modules.NewGenericBufferedProcessorModule(
+			"Limit Processor Module", "1.0.0", "limit", specificId,
+			nil, nil),
+		0,
+		0,
+	}
+	limitProcessorModule.SetBufferFunc(limitProcessorModule.limitItem)
+	limitProcessorModule.SetFlushFunc(limitProcessorModule.resetCount)
+
+	return limitProcessorModule
+}
+
+func (m *LimitProcessorModule) Configure(params *base_modules.ParameterMap) error {
+	countParam, ok := (*params)["count"]
+	if !ok || countParam == "" {
+		return fmt.Errorf("required count parameter not found")
+	}
+
+	count, err := strconv.Atoi(countParam)
+	if err != nil || count < 1 {
+		return fmt.Errorf("invalid count parameter %q", countParam)
+	}
+
+	m.count = count
+
+	m.SetReady(true)
+
+	return nil
+}
+
+func (m *LimitProcessorModule) Parameters() *base_modules.ParameterMap {
+	return &base_modules.ParameterMap{
+		"count": "",
+	}
+}
+
+func (m *LimitProcessorModule) Duplicate(specificId string) (base_modules.Module, error) {
+	duplicate := NewLimitProcessorModule(specificId)
+	err := pipeliner_modules.RegisterPipelinerProcessorModule(duplicate)
+	if err != nil {
+		return nil, err
+	}
+
+	return duplicate, nil
+}
+
+func (m *LimitProcessorModule) limitItem(
+	item *datatypes.PipelineItem) []*datatypes.PipelineItem {
+	if m.seen >= m.count {
+		m.Drop(item, fmt.Sprintf("limit of %d items reached", m.count))
+		return nil
+	}
+
+	m.seen++
+
+	return []*datatypes.PipelineItem{item}
+}
+
+func (m *LimitProcessorModule) resetCount(bool) []*datatypes.PipelineItem {
+	m.seen = 0
+
+	return nil
+}
+
+func init() {
+	pipeliner_modules.RegisterPipelinerProcessorModule(
+		NewLimitProcessorModule(""))
+}
@@ -0,0 +1,501 @@
+package input
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+	"github.com/brunoga/go-pipeliner/expr"
+	"github.com/kylelemons/go-gypsy/yaml"
+
+	pipeliner_modules "github.com/brunoga/go-pipeliner/modules"
+	base_modules "gopkg.in/brunoga/go-modules.v1"
+)
+
+// lookupReloadInterval is the minimum time between checks for changes to the
+// table file.
+const lookupReloadInterval = time.Second
+
+// LookupProcessorModule enriches items with data from a local CSV, JSON or
+// YAML table. The key expression (see the expr package) is evaluated for each
+// item and the table row with the same value in the key column is merged into
+// the item metadata (except for the key column itself). If the key expression
+// evaluates to a list, the first element with a matching row is used.
+//
+// CSV tables must have a header row. JSON tables can be lists of objects or
+// objects mapping keys to objects. YAML tables can be lists of maps or maps of
+// maps.
+type LookupProcessorModule struct {
+	*pipeliner_modules.GenericProcessorModule
+
+	path          string
+	format        string
+	key           *expr.Expression
+	keyColumn     string
+	prefix        string
+	ignoreCase    bool
+	dropUnmatched bool
+	reload        bool
+
+	tableMutex sync.RWMutex
+	table      map[string]datatypes.Metadata
+	modTime    time.Time
+	lastCheck  time.Time
+}
+
+func NewLookupProcessorModule(specificId string) *LookupProcessorModule {
+	lookupProcessorModule := &LookupProcessorModule{
+		pipeliner_modules.NewGenericProcessorModule(
+			"Lookup Processor Module", "1.0.0", "lookup",
+			specificId, nil),
+		"",
+		"",
+		nil,
+		"",
+		"",
+		false,
+		false,
+		false,
+		sync.RWMutex{},
+		nil,
+		time.Time{},
+		time.Time{},
+	}
+	lookupProcessorModule.SetProcessorFunc(
+		lookupProcessorModule.lookupItem)
+
+	return lookupProcessorModule
+}
+
+func (m *LookupProcessorModule) Configure(params *base_modules.ParameterMap) error {
+	path, ok := (*params)["path"]
+	if !ok || path == "" {
+		return fmt.Errorf("required path parameter not found")
+	}
+
+	format := (*params)["format"]
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	switch format {
+	case "csv", "json", "yaml":
+	case "yml":
+		format = "yaml"
+	default:
+		return fmt.Errorf("invalid format parameter %q (must be csv, json "+
+			"or yaml)", format)
+	}
+
+	keyParam, ok := (*params)["key"]
+	if !ok || keyParam == "" {
+		return fmt.Errorf("required key parameter not found")
+	}
+
+	key, err := expr.Compile(keyParam)
+	if err != nil {
+		return fmt.Errorf("invalid key parameter : %v", err)
+	}
+
+	keyColumn := (*params)["key_column"]
+	if keyColumn == "" {
+		return fmt.Errorf("invalid empty key_column parameter")
+	}
+
+	ignoreCaseParam := (*params)["ignore_case"]
+	ignoreCase, err := strconv.ParseBool(ignoreCaseParam)
+	if err != nil {
+		return fmt.Errorf("invalid ignore_case parameter %q",
+			ignoreCaseParam)
+	}
+
+	dropUnmatched := false
+	switch unmatchedParam := (*params)["unmatched"]; unmatchedParam {
+	case "keep":
+	case "drop":
+		dropUnmatched = true
+	default:
+		return fmt.Errorf("invalid unmatched parameter %q (must be keep "+
+			"or drop)", unmatchedParam)
+	}
+
+	reloadParam := (*params)["reload"]
+	reload, err := strconv.ParseBool(reloadParam)
+	if err != nil {
+		return fmt.Errorf("invalid reload parameter %q", reloadParam)
+	}
+
+	m.path = path
+	m.format = format
+	m.key = key
+	m.keyColumn = keyColumn
+	m.prefix = (*params)["prefix"]
+	m.ignoreCase = ignoreCase
+	m.dropUnmatched = dropUnmatched
+	m.reload = reload
+
+	err = m.loadTable()
+	if err != nil {
+		return err
+	}
+
+	m.SetReady(true)
+
+	return nil
+}
+
+func (m *LookupProcessorModule) Parameters() *base_modules.ParameterMap {
+	return &base_modules.ParameterMap{
+		"path":        "",
+		"format":      "",
+		"key":         "",
+		"key_column":  "key",
+		"prefix":      "",
+		"ignore_case": "false",
+		"unmatched":   "keep",
+		"reload":      "false",
+	}
+}
+
+func (m *LookupProcessorModule) Duplicate(specificId string) (base_modules.Module, error) {
+	duplicate := NewLookupProcessorModule(specificId)
+	err := pipeliner_modules.RegisterPipelinerProcessorModule(duplicate)
+	if err != nil {
+		return nil, err
+	}
+
+	return duplicate, nil
+}
+
+func (m *LookupProcessorModule) lookupItem(
+	item *datatypes.PipelineItem) (bool, string) {
+	if m.reload {
+		m.reloadTable()
+	}
+
+	value, err := m.key.Evaluate(item)
+	if err != nil {
+		m.Log(err)
+		return m.dropUnmatched, fmt.Sprintf("error evaluating key : %v",
+			err)
+	}
+
+	keys, ok := value.([]interface{})
+	if !ok {
+		keys = []interface{}{value}
+	}
+
+	m.tableMutex.RLock()
+	defer m.tableMutex.RUnlock()
+
+	for _, key := range keys {
+		if key == nil {
+			continue
+		}
+
+		row, ok := m.table[m.normalizeKey(expr.ToString(key))]
+		if !ok {
+			continue
+		}
+
+		metadata := make(datatypes.Metadata, len(row))
+		for column, columnValue := range row {
+			metadata[m.prefix+column] = columnValue
+		}
+		item.MergeMetadata(metadata)
+
+		return false, ""
+	}
+
+	return m.dropUnmatched, fmt.Sprintf("no row in %q for key %q", m.path,
+		expr.ToString(value))
+}
+
+// reloadTable reloads the table if the file changed since it was loaded.
+func (m *LookupProcessorModule) reloadTable() {
+	m.tableMutex.Lock()
+	if time.Since(m.lastCheck) < lookupReloadInterval {
+		m.tableMutex.Unlock()
+		return
+	}
+	m.lastCheck = time.Now()
+	modTime := m.modTime
+	m.tableMutex.Unlock()
+
+	info, err := os.Stat(m.path)
+	if err != nil {
+		m.Log(fmt.Errorf("error checking %q : %v", m.path, err))
+		return
+	}
+
+	if info.ModTime().Equal(modTime) {
+		return
+	}
+
+	err = m.loadTable()
+	if err != nil {
+		// Keep using the previous table.
+		m.Log(err)
+	}
+}
+
+// loadTable loads the table file.
+func (m *LookupProcessorModule) loadTable() error {
+	info, err := os.Stat(m.path)
+	if err != nil {
+		return fmt.Errorf("error loading %q : %v", m.path, err)
+	}
+
+	var rows []datatypes.Metadata
+	switch m.format {
+	case "csv":
+		rows, err = readCsvTable(m.path)
+	case "json":
+		rows, err = readJsonTable(m.path, m.keyColumn)
+	default:
+		rows, err = readYamlTable(m.path, m.keyColumn)
+	}
+	if err != nil {
+		return fmt.Errorf("error loading %q : %v", m.path, err)
+	}
+
+	table := make(map[string]datatypes.Metadata, len(rows))
+	for i, row := range rows {
+		key, ok := row[m.keyColumn]
+		if !ok {
+			return fmt.Errorf("error loading %q : row %d has no %q column",
+				m.path, i+1, m.keyColumn)
+		}
+		delete(row, m.keyColumn)
+
+		table[m.normalizeKey(expr.ToString(key))] = row
+	}
+
+	m.tableMutex.Lock()
+	m.table = table
+	m.modTime = info.ModTime()
+	m.tableMutex.Unlock()
+
+	return nil
+}
+
+func (m *LookupProcessorModule) normalizeKey(key string) string {
+	if m.ignoreCase {
+		return strings.ToLower(key)
+	}
+
+	return key
+}
+
+// readCsvTable reads a CSV table with a header row. Empty cells are ignored.
+func readCsvTable(path string) ([]datatypes.Metadata, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("missing header row")
+	}
+
+	header := records[0]
+
+	rows := make([]datatypes.Metadata, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(datatypes.Metadata)
+		for i, value := range record {
+			if value != "" {
+				row[strings.TrimSpace(header[i])] = value
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// readJsonTable reads a JSON table that is either a list of objects or an
+// object mapping keys to objects. Values must be scalars or lists of scalars.
+// Null values are ignored.
+func readJsonTable(path, keyColumn string) ([]datatypes.Metadata, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []datatypes.Metadata
+
+	var list []map[string]interface{}
+	err = json.Unmarshal(data, &list)
+	if err == nil {
+		for i, element := range list {
+			row, err := jsonTableRow(element)
+			if err != nil {
+				return nil, fmt.Errorf("row %d : %v", i+1, err)
+			}
+			rows = append(rows, row)
+		}
+
+		return rows, nil
+	}
+
+	var object map[string]map[string]interface{}
+	if json.Unmarshal(data, &object) != nil {
+		return nil, fmt.Errorf("expected a list of objects or an object " +
+			"of objects")
+	}
+
+	for key, element := range object {
+		row, err := jsonTableRow(element)
+		if err != nil {
+			return nil, fmt.Errorf("row %q : %v", key, err)
+		}
+		row[keyColumn] = key
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func jsonTableRow(element map[string]interface{}) (datatypes.Metadata, error) {
+	if element == nil {
+		return nil, fmt.Errorf("expected an object")
+	}
+
+	row := make(datatypes.Metadata, len(element))
+	for column, value := range element {
+		if value == nil {
+			continue
+		}
+
+		rowValue, err := jsonTableValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("column %q has an invalid value : %v",
+				column, err)
+		}
+		row[column] = rowValue
+	}
+
+	return row, nil
+}
+
+// jsonTableValue converts integral numbers to int64 and lists of strings to
+// []string so they can be used like other metadata values. Objects and lists
+// of anything other than scalars are rejected.
+func jsonTableValue(value interface{}) (interface{}, error) {
+	switch typedValue := value.(type) {
+	case float64:
+		if typedValue == math.Trunc(typedValue) &&
+			math.Abs(typedValue) < 1<<53 {
+			return int64(typedValue), nil
+		}
+	case map[string]interface{}:
+		return nil, fmt.Errorf("objects are not supported")
+	case []interface{}:
+		allStrings := true
+		for i, element := range typedValue {
+			switch element.(type) {
+			case string:
+			case bool, float64, nil:
+				allStrings = false
+			default:
+				return nil, fmt.Errorf("element %d is not a scalar",
+					i+1)
+			}
+		}
+		if !allStrings {
+			return value, nil
+		}
+
+		strings := make([]string, len(typedValue))
+		for i, element := range typedValue {
+			strings[i] = element.(string)
+		}
+		return strings, nil
+	}
+
+	return value, nil
+}
+
+// readYamlTable reads a YAML table that is either a list of maps or a map of
+// maps. Values must be scalars or lists of scalars.
+func readYamlTable(path, keyColumn string) ([]datatypes.Metadata, error) {
+	file, err := yaml.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []datatypes.Metadata
+	switch root := file.Root.(type) {
+	case yaml.List:
+		for i, node := range root {
+			row, err := yamlTableRow(node)
+			if err != nil {
+				return nil, fmt.Errorf("row %d : %v", i+1, err)
+			}
+			rows = append(rows, row)
+		}
+	case yaml.Map:
+		for key, node := range root {
+			row, err := yamlTableRow(node)
+			if err != nil {
+				return nil, fmt.Errorf("row %q : %v", key, err)
+			}
+			row[keyColumn] = key
+			rows = append(rows, row)
+		}
+	default:
+		return nil, fmt.Errorf("expected a list of maps or a map of maps")
+	}
+
+	return rows, nil
+}
+
+func yamlTableRow(node yaml.Node) (datatypes.Metadata, error) {
+	nodeMap, ok := node.(yaml.Map)
+	if !ok {
+		return nil, fmt.Errorf("expected a map")
+	}
+
+	row := make(datatypes.Metadata, len(nodeMap))
+	for column, valueNode := range nodeMap {
+		switch value := valueNode.(type) {
+		case yaml.Scalar:
+			row[column] = value.String()
+		case yaml.List:
+			values := make([]string, 0, len(value))
+			for _, elementNode := range value {
+				element, ok := elementNode.(yaml.Scalar)
+				if !ok {
+					return nil, fmt.Errorf("column %q has an "+
+						"invalid value", column)
+				}
+				values = append(values, element.String())
+			}
+			row[column] = values
+		default:
+			return nil, fmt.Errorf("column %q has an invalid value",
+				column)
+		}
+	}
+
+	return row, nil
+}
+
+func init() {
+	pipeliner_modules.RegisterPipelinerProcessorModule(
+		NewLookupProcessorModule(""))
+}
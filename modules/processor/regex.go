@@ -0,0 +1,217 @@
+package input
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+	"github.com/brunoga/go-pipeliner/expr"
+
+	pipeliner_modules "github.com/brunoga/go-pipeliner/modules"
+	base_modules "gopkg.in/brunoga/go-modules.v1"
+)
+
+// RegexProcessorModule filters items by matching regular expressions against
+// one of their fields. Items matching any exclude pattern are dropped. Other
+// items are let through if they match any include pattern (the first matching
+// pattern wins) or, in "all" mode, all include patterns. If there are no
+// include patterns, all items not excluded are let through. For fields with
+// multiple values (for example, "urls"), a pattern matches if it matches any
+// of the values.
+type RegexProcessorModule struct {
+	*pipeliner_modules.GenericProcessorModule
+
+	field    *expr.Field
+	include  []*regexp.Regexp
+	exclude  []*regexp.Regexp
+	matchAll bool
+}
+
+func NewRegexProcessorModule(specificId string) *RegexProcessorModule {
+	regexProcessorModule := &RegexProcessorModule{
+		pipeliner_modules.NewGenericProcessorModule(
+			"Regex Processor Module", "1.0.0", "regex",
+			specificId, nil),
+		nil,
+		nil,
+		nil,
+		false,
+	}
+	regexProcessorModule.SetProcessorFunc(
+		regexProcessorModule.filterItem)
+
+	return regexProcessorModule
+}
+
+func (m *RegexProcessorModule) Configure(params *base_modules.ParameterMap) error {
+	fieldParam := (*params)["field"]
+	if fieldParam == "" {
+		fieldParam = "name"
+	}
+
+	field, err := expr.CompileField(fieldParam)
+	if err != nil {
+		return fmt.Errorf("invalid field parameter : %v", err)
+	}
+
+	ignoreCase := false
+	ignoreCaseParam := (*params)["ignore_case"]
+	if ignoreCaseParam != "" {
+		ignoreCase, err = strconv.ParseBool(ignoreCaseParam)
+		if err != nil {
+			return fmt.Errorf("invalid ignore_case parameter %q",
+				ignoreCaseParam)
+		}
+	}
+
+	include, err := compilePatterns((*params)["include"], ignoreCase)
+	if err != nil {
+		return fmt.Errorf("invalid include parameter : %v", err)
+	}
+
+	exclude, err := compilePatterns((*params)["exclude"], ignoreCase)
+	if err != nil {
+		return fmt.Errorf("invalid exclude parameter : %v", err)
+	}
+
+	if len(include) == 0 && len(exclude) == 0 {
+		return fmt.Errorf("at least one include or exclude pattern is " +
+			"required")
+	}
+
+	matchAll := false
+	switch matchParam := (*params)["match"]; matchParam {
+	case "", "any":
+	case "all":
+		matchAll = true
+	default:
+		return fmt.Errorf("invalid match parameter %q (must be any or all)",
+			matchParam)
+	}
+
+	m.field = field
+	m.include = include
+	m.exclude = exclude
+	m.matchAll = matchAll
+
+	m.SetReady(true)
+
+	return nil
+}
+
+func (m *RegexProcessorModule) Parameters() *base_modules.ParameterMap {
+	return &base_modules.ParameterMap{
+		"field":       "name",
+		"include":     "",
+		"exclude":     "",
+		"match":       "any",
+		"ignore_case": "false",
+	}
+}
+
+func (m *RegexProcessorModule) Duplicate(specificId string) (base_modules.Module, error) {
+	duplicate := NewRegexProcessorModule(specificId)
+	err := pipeliner_modules.RegisterPipelinerProcessorModule(duplicate)
+	if err != nil {
+		return nil, err
+	}
+
+	return duplicate, nil
+}
+
+func (m *RegexProcessorModule) filterItem(
+	item *datatypes.PipelineItem) (bool, string) {
+	var values []string
+	value := m.field.Value(item)
+	if value != nil {
+		values = fieldStrings(value)
+	}
+
+	for _, pattern := range m.exclude {
+		if matchAny(pattern, values) {
+			return true, fmt.Sprintf("%s matches exclude pattern %q",
+				m.field, pattern)
+		}
+	}
+
+	if len(m.include) == 0 {
+		return false, ""
+	}
+
+	for _, pattern := range m.include {
+		matched := matchAny(pattern, values)
+		if matched && !m.matchAll {
+			return false, ""
+		}
+		if !matched && m.matchAll {
+			return true, fmt.Sprintf("%s does not match include "+
+				"pattern %q", m.field, pattern)
+		}
+	}
+
+	if m.matchAll {
+		return false, ""
+	}
+
+	return true, fmt.Sprintf("%s does not match any include pattern", m.field)
+}
+
+// compilePatterns compiles the given newline-separated list of regular
+// expressions. Empty lines are ignored.
+func compilePatterns(patternsParam string,
+	ignoreCase bool) ([]*regexp.Regexp, error) {
+	var patterns []*regexp.Regexp
+	for _, patternString := range strings.Split(patternsParam, "\n") {
+		if strings.TrimSpace(patternString) == "" {
+			continue
+		}
+
+		if ignoreCase {
+			patternString = "(?i)" + patternString
+		}
+
+		pattern, err := regexp.Compile(patternString)
+		if err != nil {
+			return nil, err
+		}
+
+		patterns = append(patterns, pattern)
+	}
+
+	return patterns, nil
+}
+
+// fieldStrings returns the string representation of the given field value or,
+// for slices, of each of its elements.
+func fieldStrings(value interface{}) []string {
+	reflectValue := reflect.ValueOf(value)
+	if reflectValue.Kind() != reflect.Slice &&
+		reflectValue.Kind() != reflect.Array {
+		return []string{expr.ToString(value)}
+	}
+
+	values := make([]string, reflectValue.Len())
+	for i := range values {
+		values[i] = expr.ToString(reflectValue.Index(i).Interface())
+	}
+
+	return values
+}
+
+func matchAny(pattern *regexp.Regexp, values []string) bool {
+	for _, value := range values {
+		if pattern.MatchString(value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func init() {
+	pipeliner_modules.RegisterPipelinerProcessorModule(
+		NewRegexProcessorModule(""))
+}
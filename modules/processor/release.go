@@ -0,0 +1,253 @@
+package input
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// releaseInfo is the information extracted from a release name.
+type releaseInfo struct {
+	series     string
+	season     int
+	episode    int
+	endEpisode int
+	date       time.Time
+	resolution string
+	source     string
+	codec      string
+	group      string
+	proper     bool
+	repack     bool
+}
+
+var (
+	releaseExtensionRegexp = regexp.MustCompile(
+		`(?i)\.(mkv|mp4|avi|m4v|wmv|mov|torrent|nzb)$`)
+
+	// Episode markers, in the order they are tried.
+	releaseEpisodeRegexp = regexp.MustCompile(
+		`(?i)\bS(\d{1,2})[ .-]?E(\d{1,3})(?:(?:-?E|-)(\d{1,3}))?\b`)
+	releaseCrossRegexp = regexp.MustCompile(`(?i)\b(\d{1,2})x(\d{2,3})\b`)
+	releaseDateRegexp  = regexp.MustCompile(
+		`\b((?:19|20)\d{2})[ .-](\d{2})[ .-](\d{2})\b`)
+	releaseSeasonRegexp = regexp.MustCompile(
+		`(?i)\b(?:S(\d{1,2})|Season[ .-]?(\d{1,2}))\b`)
+
+	releaseResolutionRegexp = regexp.MustCompile(
+		`(?i)\b(480p|576p|720p|1080[pi]|2160p|4k|uhd)\b`)
+	releaseSourceRegexp = regexp.MustCompile(
+		`(?i)\b(web[ .-]?dl|web[ .-]?rip|web|hdtv|pdtv|sdtv|blu[ .-]?ray|` +
+			`bd[ .-]?rip|br[ .-]?rip|dvd[ .-]?rip|dvd|hd[ .-]?rip|` +
+			`cam|telesync)\b`)
+	releaseCodecRegexp = regexp.MustCompile(
+		`(?i)\b(x264|x265|h[ .]?264|h[ .]?265|hevc|avc|xvid|divx|av1)\b`)
+	releaseGroupRegexp  = regexp.MustCompile(`-([A-Za-z0-9]+)(?:\[[^\]]*\])?$`)
+	releaseProperRegexp = regexp.MustCompile(`(?i)\bproper\b`)
+	releaseRepackRegexp = regexp.MustCompile(`(?i)\b(repack|rerip)\b`)
+
+	releaseSeparatorRegexp = regexp.MustCompile(`[ ._]+`)
+)
+
+// releaseSources maps lowercase sources (without separators) to their
+// canonical names.
+var releaseSources = map[string]string{
+	"webdl":    "WEB-DL",
+	"webrip":   "WEBRip",
+	"web":      "WEB",
+	"hdtv":     "HDTV",
+	"pdtv":     "PDTV",
+	"sdtv":     "SDTV",
+	"bluray":   "BluRay",
+	"bdrip":    "BDRip",
+	"brrip":    "BRRip",
+	"dvdrip":   "DVDRip",
+	"dvd":      "DVD",
+	"hdrip":    "HDRip",
+	"cam":      "CAM",
+	"telesync": "TS",
+}
+
+// releaseCodecs maps lowercase codecs (without separators) to their canonical
+// names.
+var releaseCodecs = map[string]string{
+	"x264": "x264",
+	"x265": "x265",
+	"h264": "H.264",
+	"avc":  "H.264",
+	"h265": "H.265",
+	"hevc": "H.265",
+	"xvid": "XviD",
+	"divx": "DivX",
+	"av1":  "AV1",
+}
+
+// parseReleaseName parses a TV episode release name like
+// "Some.Show.S01E02.720p.WEB-DL.x264-GROUP". It returns the parsed information
+// and true if an episode, date or season marker was found or nil and false
+// otherwise.
+func parseReleaseName(name string) (*releaseInfo, bool) {
+	name = releaseExtensionRegexp.ReplaceAllString(strings.TrimSpace(name), "")
+
+	// Underscores are word characters for regular expressions, so they
+	// would prevent \b from matching.
+	name = strings.ReplaceAll(name, "_", ".")
+
+	info := &releaseInfo{}
+
+	var marker []int
+	if match := releaseEpisodeRegexp.FindStringSubmatchIndex(name); match != nil {
+		marker = match
+		info.season = atoiRange(name, match[2], match[3])
+		info.episode = atoiRange(name, match[4], match[5])
+		info.endEpisode = atoiRange(name, match[6], match[7])
+	} else if match := releaseCrossRegexp.FindStringSubmatchIndex(name); match != nil {
+		marker = match
+		info.season = atoiRange(name, match[2], match[3])
+		info.episode = atoiRange(name, match[4], match[5])
+	} else if match := releaseDateRegexp.FindStringSubmatchIndex(name); match != nil {
+		date, err := time.Parse("2006-01-02", name[match[2]:match[3]]+"-"+
+			name[match[4]:match[5]]+"-"+name[match[6]:match[7]])
+		if err == nil {
+			marker = match
+			info.date = date
+		}
+	}
+	if marker == nil {
+		match := releaseSeasonRegexp.FindStringSubmatchIndex(name)
+		if match == nil {
+			return nil, false
+		}
+		marker = match
+		info.season = atoiRange(name, match[2], match[3]) +
+			atoiRange(name, match[4], match[5])
+	}
+
+	if info.endEpisode <= info.episode {
+		info.endEpisode = 0
+	}
+
+	info.series = strings.Trim(releaseSeparatorRegexp.ReplaceAllString(
+		name[:marker[0]], " "), " -[]()")
+
+	rest := name[marker[1]:]
+
+	resolution := strings.ToLower(releaseResolutionRegexp.FindString(rest))
+	switch resolution {
+	case "4k", "uhd":
+		resolution = "2160p"
+	}
+	info.resolution = resolution
+
+	info.source = releaseSources[normalizeReleaseToken(
+		releaseSourceRegexp.FindString(rest))]
+	info.codec = releaseCodecs[normalizeReleaseToken(
+		releaseCodecRegexp.FindString(rest))]
+
+	// A trailing source like "WEB-DL" looks like a group, but it is not.
+	if match := releaseGroupRegexp.FindStringSubmatchIndex(rest); match != nil &&
+		!insideReleaseToken(rest, match[0]) {
+		info.group = rest[match[2]:match[3]]
+	}
+
+	info.proper = releaseProperRegexp.MatchString(rest)
+	info.repack = releaseRepackRegexp.MatchString(rest)
+
+	return info, true
+}
+
+// episodeId returns an identifier for the episode (or episodes, or season)
+// like "S01E02", "S01E02-E03", "2024-01-31" or "S01".
+func (r *releaseInfo) episodeId() string {
+	switch {
+	case !r.date.IsZero():
+		return r.date.Format("2006-01-02")
+	case r.episode == 0:
+		return "S" + twoDigits(r.season)
+	case r.endEpisode != 0:
+		return "S" + twoDigits(r.season) + "E" + twoDigits(r.episode) +
+			"-E" + twoDigits(r.endEpisode)
+	}
+
+	return "S" + twoDigits(r.season) + "E" + twoDigits(r.episode)
+}
+
+// insideReleaseToken returns true if the given position in text is part of a
+// source or codec token.
+func insideReleaseToken(text string, position int) bool {
+	for _, tokenRegexp := range []*regexp.Regexp{releaseSourceRegexp,
+		releaseCodecRegexp} {
+		for _, match := range tokenRegexp.FindAllStringIndex(text, -1) {
+			if match[0] <= position && position < match[1] {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func normalizeReleaseToken(token string) string {
+	return strings.NewReplacer(" ", "", ".", "", "-", "").Replace(
+		strings.ToLower(token))
+}
+
+func atoiRange(text string, start, end int) int {
+	if start < 0 {
+		return 0
+	}
+
+	value, _ := strconv.Atoi(text[start:end])
+
+	return value
+}
+
+func twoDigits(value int) string {
+	if value < 10 {
+		return "0" + strconv.Itoa(value)
+	}
+
+	return strconv.Itoa(value)
+}
+
+// releaseResolutionRanks ranks resolutions from worst to best.
+var releaseResolutionRanks = map[string]int{
+	"480p":  1,
+	"576p":  2,
+	"720p":  3,
+	"1080i": 4,
+	"1080p": 5,
+	"2160p": 6,
+}
+
+// releaseSourceRanks ranks canonical sources from worst to best.
+var releaseSourceRanks = map[string]int{
+	"CAM":    1,
+	"TS":     2,
+	"SDTV":   3,
+	"PDTV":   3,
+	"DVD":    4,
+	"DVDRip": 4,
+	"HDTV":   5,
+	"HDRip":  5,
+	"WEBRip": 6,
+	"WEB":    7,
+	"WEB-DL": 7,
+	"BRRip":  7,
+	"BDRip":  8,
+	"BluRay": 8,
+}
+
+// releaseQuality returns a number that is higher for better quality releases.
+// Resolution is more important than source. Propers and repacks are better
+// than other releases with the same resolution and source.
+func releaseQuality(resolution, source string, proper, repack bool) int {
+	quality := releaseResolutionRanks[resolution]*100 +
+		releaseSourceRanks[source]*10
+	if proper || repack {
+		quality++
+	}
+
+	return quality
+}
@@ -0,0 +1,149 @@
+package input
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseReleaseName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected *releaseInfo
+	}{
+		{"Some.Show.S01E02.720p.WEB-DL.x264-GROUP", &releaseInfo{
+			series: "Some Show", season: 1, episode: 2,
+			resolution: "720p", source: "WEB-DL", codec: "x264",
+			group: "GROUP"}},
+		{"Some.Show.S01E02.720p.WEB-DL", &releaseInfo{
+			series: "Some Show", season: 1, episode: 2,
+			resolution: "720p", source: "WEB-DL"}},
+		{"Show.S02.Complete.1080p.WEB-DL", &releaseInfo{
+			series: "Show", season: 2, resolution: "1080p",
+			source: "WEB-DL"}},
+		{"Show.S01E01.1080p.WEB-Rip", &releaseInfo{
+			series: "Show", season: 1, episode: 1, resolution: "1080p",
+			source: "WEBRip"}},
+		{"Show.S01E01.1080p.BLU-RAY", &releaseInfo{
+			series: "Show", season: 1, episode: 1, resolution: "1080p",
+			source: "BluRay"}},
+		{"Show.S01E01.1080p.WEB-DL-GRP", &releaseInfo{
+			series: "Show", season: 1, episode: 1, resolution: "1080p",
+			source: "WEB-DL", group: "GRP"}},
+		{"Show.S01E01.720p.WEB-GRP", &releaseInfo{
+			series: "Show", season: 1, episode: 1, resolution: "720p",
+			source: "WEB", group: "GRP"}},
+		{"Show.S01E01.720p.HDTV.x264-GRP[rarbg]", &releaseInfo{
+			series: "Show", season: 1, episode: 1, resolution: "720p",
+			source: "HDTV", codec: "x264", group: "GRP"}},
+		{"Show.S01E01.720p.HDTV.x264-GRP.mkv", &releaseInfo{
+			series: "Show", season: 1, episode: 1, resolution: "720p",
+			source: "HDTV", codec: "x264", group: "GRP"}},
+		{"show_s01e05_hdtv_xvid-grp", &releaseInfo{
+			series: "show", season: 1, episode: 5, source: "HDTV",
+			codec: "XviD", group: "grp"}},
+		{"Show S01E01E02 1080p HEVC", &releaseInfo{
+			series: "Show", season: 1, episode: 1, endEpisode: 2,
+			resolution: "1080p", codec: "H.265"}},
+		{"Show.S01E01-E03.720p", &releaseInfo{
+			series: "Show", season: 1, episode: 1, endEpisode: 3,
+			resolution: "720p"}},
+		{"Show.S01E01-03.720p", &releaseInfo{
+			series: "Show", season: 1, episode: 1, endEpisode: 3,
+			resolution: "720p"}},
+		{"Show 3x07 HDTV", &releaseInfo{
+			series: "Show", season: 3, episode: 7, source: "HDTV"}},
+		{"Show.2024.01.31.720p.WEB.H264-GRP", &releaseInfo{
+			series: "Show", date: time.Date(2024, 1, 31, 0, 0, 0, 0,
+				time.UTC), resolution: "720p", source: "WEB",
+			codec: "H.264", group: "GRP"}},
+		{"Show.2019.S01E01.2160p.UHD", &releaseInfo{
+			series: "Show 2019", season: 1, episode: 1,
+			resolution: "2160p"}},
+		{"Show.Season.3.1080p", &releaseInfo{
+			series: "Show", season: 3, resolution: "1080p"}},
+		{"Show.S01E01.PROPER.720p.HDTV.x264-GRP", &releaseInfo{
+			series: "Show", season: 1, episode: 1, resolution: "720p",
+			source: "HDTV", codec: "x264", group: "GRP", proper: true}},
+		{"Show.S01E01.REPACK.720p.HDTV.x264-GRP", &releaseInfo{
+			series: "Show", season: 1, episode: 1, resolution: "720p",
+			source: "HDTV", codec: "x264", group: "GRP", repack: true}},
+	}
+
+	for _, test := range tests {
+		info, ok := parseReleaseName(test.name)
+		if !ok {
+			t.Errorf("parseReleaseName(%q) : not parsed", test.name)
+			continue
+		}
+
+		if *info != *test.expected {
+			t.Errorf("parseReleaseName(%q) = %+v, expected %+v", test.name,
+				*info, *test.expected)
+		}
+	}
+}
+
+func TestParseReleaseNameUnparsed(t *testing.T) {
+	for _, name := range []string{
+		"",
+		"Some.Movie.2019.1080p.BluRay.x264-GRP",
+		"ubuntu-22.04-desktop-amd64.iso",
+		"Show.2024.13.45.720p",
+	} {
+		info, ok := parseReleaseName(name)
+		if ok {
+			t.Errorf("parseReleaseName(%q) = %+v, expected no match", name,
+				*info)
+		}
+	}
+}
+
+func TestEpisodeId(t *testing.T) {
+	tests := []struct {
+		info     releaseInfo
+		expected string
+	}{
+		{releaseInfo{season: 1, episode: 2}, "S01E02"},
+		{releaseInfo{season: 10, episode: 120}, "S10E120"},
+		{releaseInfo{season: 1, episode: 2, endEpisode: 3}, "S01E02-E03"},
+		{releaseInfo{season: 2}, "S02"},
+		{releaseInfo{date: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)},
+			"2024-01-31"},
+	}
+
+	for _, test := range tests {
+		episodeId := test.info.episodeId()
+		if episodeId != test.expected {
+			t.Errorf("episodeId(%+v) = %q, expected %q", test.info,
+				episodeId, test.expected)
+		}
+	}
+}
+
+func TestReleaseQuality(t *testing.T) {
+	ordered := []struct {
+		resolution, source string
+		proper             bool
+	}{
+		{"", "", false},
+		{"480p", "HDTV", false},
+		{"720p", "CAM", false},
+		{"720p", "HDTV", false},
+		{"720p", "HDTV", true},
+		{"720p", "WEB-DL", false},
+		{"1080p", "HDTV", false},
+		{"2160p", "WEB", false},
+	}
+
+	previous := -1
+	for _, release := range ordered {
+		quality := releaseQuality(release.resolution, release.source,
+			release.proper, false)
+		if quality <= previous {
+			t.Errorf("releaseQuality(%q, %q, %v) = %d, expected more than "+
+				"%d", release.resolution, release.source, release.proper,
+				quality, previous)
+		}
+		previous = quality
+	}
+}
@@ -0,0 +1,425 @@
+package input
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+	"github.com/brunoga/go-pipeliner/expr"
+
+	pipeliner_modules "github.com/brunoga/go-pipeliner/modules"
+	base_modules "gopkg.in/brunoga/go-modules.v1"
+)
+
+// rewriteFunctions are the functions available to rewrite templates.
+var rewriteFunctions = template.FuncMap{
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
+	"trim":    strings.TrimSpace,
+	"replace": strings.ReplaceAll,
+}
+
+// rewriteRule is a single rewrite rule. It either applies a regular
+// expression substitution or a template to a field.
+type rewriteRule struct {
+	source string
+	field  string
+
+	pattern     *regexp.Regexp
+	replacement string
+	global      bool
+
+	template *template.Template
+}
+
+// rewriteData is the data available to rewrite templates.
+type rewriteData struct {
+	Name        string
+	Description string
+	Date        time.Time
+	Url         string
+	Urls        []string
+	Metadata    map[string]string
+	Payload     map[string]interface{}
+
+	// Value is the current value of the field being rewritten.
+	Value string
+}
+
+// RewriteProcessorModule rewrites item fields (name, description, url, urls
+// and metadata.<key>) according to an ordered list of rules. Each rule has the
+// form "<field> = s/<regex>/<replacement>/[flags]" for regular expression
+// substitutions (any punctuation character can be used instead of "/", flags
+// are "g" to replace all matches and "i" for case-insensitive matching) or
+// "<field> = <template>" for text/template templates.
+type RewriteProcessorModule struct {
+	*pipeliner_modules.GenericProcessorModule
+
+	rules []*rewriteRule
+}
+
+func NewRewriteProcessorModule(specificId string) *RewriteProcessorModule {
+	rewriteProcessorModule := &RewriteProcessorModule{
+		pipeliner_modules.NewGenericProcessorModule(
+			"Rewrite Processor Module", "1.0.0", "rewrite",
+			specificId, nil),
+		nil,
+	}
+	rewriteProcessorModule.SetProcessorFunc(
+		rewriteProcessorModule.rewriteItem)
+
+	return rewriteProcessorModule
+}
+
+func (m *RewriteProcessorModule) Configure(params *base_modules.ParameterMap) error {
+	rulesParam, ok := (*params)["rules"]
+	if !ok || strings.TrimSpace(rulesParam) == "" {
+		return fmt.Errorf("required rules parameter not found")
+	}
+
+	var rules []*rewriteRule
+	for _, ruleString := range strings.Split(rulesParam, "\n") {
+		if strings.TrimSpace(ruleString) == "" {
+			continue
+		}
+
+		rule, err := parseRewriteRule(ruleString)
+		if err != nil {
+			return fmt.Errorf("invalid rule %q : %v", ruleString, err)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	m.rules = rules
+
+	m.SetReady(true)
+
+	return nil
+}
+
+func (m *RewriteProcessorModule) Parameters() *base_modules.ParameterMap {
+	return &base_modules.ParameterMap{
+		"rules": "",
+	}
+}
+
+func (m *RewriteProcessorModule) Duplicate(specificId string) (base_modules.Module, error) {
+	duplicate := NewRewriteProcessorModule(specificId)
+	err := pipeliner_modules.RegisterPipelinerProcessorModule(duplicate)
+	if err != nil {
+		return nil, err
+	}
+
+	return duplicate, nil
+}
+
+func (m *RewriteProcessorModule) rewriteItem(
+	item *datatypes.PipelineItem) (bool, string) {
+	for _, rule := range m.rules {
+		err := rule.apply(item)
+		if err != nil {
+			err = fmt.Errorf("error applying rule %q : %v", rule.source,
+				err)
+			m.DeadLetter(item, err)
+			return true, err.Error()
+		}
+	}
+
+	return false, ""
+}
+
+// parseRewriteRule parses a rule in the "<field> = <rewrite>" format.
+func parseRewriteRule(ruleString string) (*rewriteRule, error) {
+	fieldString, rewriteString, ok := strings.Cut(ruleString, "=")
+	if !ok {
+		return nil, fmt.Errorf("expected \"<field> = <rewrite>\"")
+	}
+
+	field := strings.TrimSpace(fieldString)
+	switch {
+	case field == "name", field == "description", field == "url",
+		field == "urls":
+	case strings.HasPrefix(field, "metadata.") &&
+		len(field) > len("metadata."):
+	default:
+		return nil, fmt.Errorf("unsupported field %q (must be name, "+
+			"description, url, urls or metadata.<key>)", field)
+	}
+
+	rule := &rewriteRule{
+		source: strings.TrimSpace(ruleString),
+		field:  field,
+	}
+
+	rewriteString = strings.TrimSpace(rewriteString)
+	if isSubstitution(rewriteString) {
+		err := rule.parseSubstitution(rewriteString)
+		if err != nil {
+			return nil, err
+		}
+
+		return rule, nil
+	}
+
+	var err error
+	rule.template, err = template.New(field).Funcs(rewriteFunctions).Option(
+		"missingkey=zero").Parse(rewriteString)
+	if err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+// isSubstitution returns true if the given string looks like a substitution
+// ("s" followed by a punctuation delimiter).
+func isSubstitution(rewriteString string) bool {
+	return len(rewriteString) > 1 && rewriteString[0] == 's' &&
+		strings.ContainsRune("/|#,!:;@%", rune(rewriteString[1]))
+}
+
+// parseSubstitution parses a "s/<regex>/<replacement>/[flags]" substitution.
+// The delimiter can be escaped with a backslash.
+func (r *rewriteRule) parseSubstitution(substitution string) error {
+	delimiter := substitution[1]
+
+	var parts []string
+	var current strings.Builder
+	for i := 2; i < len(substitution); i++ {
+		c := substitution[i]
+		switch {
+		case c == '\\' && i+1 < len(substitution) &&
+			substitution[i+1] == delimiter:
+			current.WriteByte(delimiter)
+			i++
+		case c == delimiter:
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	parts = append(parts, current.String())
+
+	if len(parts) != 3 {
+		return fmt.Errorf("expected s%c<regex>%c<replacement>%c[flags]",
+			delimiter, delimiter, delimiter)
+	}
+
+	patternString := parts[0]
+	for _, flag := range parts[2] {
+		switch flag {
+		case 'g':
+			r.global = true
+		case 'i':
+			patternString = "(?i)" + patternString
+		default:
+			return fmt.Errorf("unknown flag %q", flag)
+		}
+	}
+
+	pattern, err := regexp.Compile(patternString)
+	if err != nil {
+		return err
+	}
+
+	r.pattern = pattern
+	r.replacement = parts[1]
+
+	return nil
+}
+
+// apply applies the rule to the given item. Fields are only set if their
+// value changed.
+func (r *rewriteRule) apply(item *datatypes.PipelineItem) error {
+	switch {
+	case r.field == "name":
+		value, err := r.rewrite(item, item.GetName())
+		if err != nil {
+			return err
+		}
+		if value != item.GetName() {
+			item.SetName(value)
+		}
+	case r.field == "description":
+		value, err := r.rewrite(item, item.GetDescription())
+		if err != nil {
+			return err
+		}
+		if value != item.GetDescription() {
+			item.SetDescription(value)
+		}
+	case r.field == "url" || r.field == "urls":
+		for index, itemUrl := range item.GetUrls() {
+			value, err := r.rewrite(item, itemUrl.String())
+			if err != nil {
+				return err
+			}
+
+			if value != itemUrl.String() {
+				newUrl, err := url.Parse(value)
+				if err != nil {
+					return err
+				}
+
+				item.SetUrl(index, newUrl)
+			}
+
+			if r.field == "url" {
+				break
+			}
+		}
+	default:
+		return r.applyMetadata(item, strings.TrimPrefix(r.field, "metadata."))
+	}
+
+	return nil
+}
+
+// applyMetadata applies the rule to the metadata value with the given key.
+// The rewritten value is converted back to the type of the current value.
+// Each element of []string values is rewritten separately.
+func (r *rewriteRule) applyMetadata(item *datatypes.PipelineItem,
+	key string) error {
+	value, ok := item.GetMetadata(key)
+	if !ok && r.template == nil {
+		// Nothing to substitute.
+		return nil
+	}
+
+	if values, ok := value.([]string); ok {
+		newValues := make([]string, len(values))
+		changed := false
+		for i, element := range values {
+			newElement, err := r.rewrite(item, element)
+			if err != nil {
+				return err
+			}
+			newValues[i] = newElement
+			changed = changed || newElement != element
+		}
+
+		if changed {
+			item.SetMetadata(key, newValues)
+		}
+
+		return nil
+	}
+
+	current := expr.ToString(value)
+
+	newString, err := r.rewrite(item, current)
+	if err != nil {
+		return err
+	}
+
+	if ok && newString == current {
+		return nil
+	}
+
+	newValue, err := convertRewritten(value, newString)
+	if err != nil {
+		return fmt.Errorf("metadata %q : %v", key, err)
+	}
+
+	item.SetMetadata(key, newValue)
+
+	return nil
+}
+
+// convertRewritten converts the given rewritten text to the type of the given
+// original value. Missing (nil) values become strings.
+func convertRewritten(original interface{}, text string) (interface{},
+	error) {
+	var value interface{}
+	var err error
+	switch original.(type) {
+	case nil, string:
+		return text, nil
+	case int64:
+		value, err = strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+	case float64:
+		value, err = strconv.ParseFloat(strings.TrimSpace(text), 64)
+	case bool:
+		value, err = strconv.ParseBool(strings.TrimSpace(text))
+	case time.Time:
+		value, err = expr.ParseTime(strings.TrimSpace(text))
+	default:
+		return nil, fmt.Errorf("can't rewrite values of type %T", original)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't convert %q to %T", text, original)
+	}
+
+	return value, nil
+}
+
+// rewrite returns the rewritten version of the given value.
+func (r *rewriteRule) rewrite(item *datatypes.PipelineItem,
+	value string) (string, error) {
+	if r.template != nil {
+		var buffer bytes.Buffer
+		err := r.template.Execute(&buffer, newRewriteData(item, value))
+		if err != nil {
+			return "", err
+		}
+
+		return buffer.String(), nil
+	}
+
+	if r.global {
+		return r.pattern.ReplaceAllString(value, r.replacement), nil
+	}
+
+	match := r.pattern.FindStringSubmatchIndex(value)
+	if match == nil {
+		return value, nil
+	}
+
+	replaced := r.pattern.ExpandString(nil, r.replacement, value, match)
+
+	return value[:match[0]] + string(replaced) + value[match[1]:], nil
+}
+
+func newRewriteData(item *datatypes.PipelineItem, value string) *rewriteData {
+	data := &rewriteData{
+		Name:        item.GetName(),
+		Description: item.GetDescription(),
+		Date:        item.GetDate(),
+		Metadata:    make(map[string]string),
+		Payload:     make(map[string]interface{}),
+		Value:       value,
+	}
+
+	for index, itemUrl := range item.GetUrls() {
+		if index == 0 {
+			data.Url = itemUrl.String()
+		}
+		data.Urls = append(data.Urls, itemUrl.String())
+	}
+
+	for key, metadataValue := range item.GetAllMetadata() {
+		data.Metadata[key] = expr.ToString(metadataValue)
+	}
+
+	for _, payloadId := range item.GetPayloadIds() {
+		payload, err := item.GetPayload(payloadId)
+		if err == nil {
+			data.Payload[payloadId] = payload
+		}
+	}
+
+	return data
+}
+
+func init() {
+	pipeliner_modules.RegisterPipelinerProcessorModule(
+		NewRewriteProcessorModule(""))
+}
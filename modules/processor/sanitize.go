@@ -0,0 +1,157 @@
+package input
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+	"github.com/brunoga/go-pipeliner/expr"
+
+	pipeliner_modules "github.com/brunoga/go-pipeliner/modules"
+	base_modules "gopkg.in/brunoga/go-modules.v1"
+)
+
+// SanitizeProcessorModule converts HTML in an item field (the description by
+// default) to plain text or to sanitized HTML, without scripts, styles,
+// tracking pixels, event handlers or unsafe links. The result can be limited
+// to a maximum length, in which case an ellipsis is added.
+type SanitizeProcessorModule struct {
+	*pipeliner_modules.GenericProcessorModule
+
+	field     string
+	plainText bool
+	maxLength int
+	ellipsis  string
+}
+
+func NewSanitizeProcessorModule(specificId string) *SanitizeProcessorModule {
+	sanitizeProcessorModule := &SanitizeProcessorModule{
+		pipeliner_modules.NewGenericProcessorModule(
+			"Sanitize Processor Module", "1.0.0", "sanitize",
+			specificId, nil),
+		"",
+		true,
+		0,
+		"",
+	}
+	sanitizeProcessorModule.SetProcessorFunc(
+		sanitizeProcessorModule.sanitizeItem)
+
+	return sanitizeProcessorModule
+}
+
+func (m *SanitizeProcessorModule) Configure(params *base_modules.ParameterMap) error {
+	field := (*params)["field"]
+	switch {
+	case field == "name", field == "description":
+	case strings.HasPrefix(field, "metadata.") &&
+		len(field) > len("metadata."):
+	default:
+		return fmt.Errorf("invalid field parameter %q (must be name, "+
+			"description or metadata.<key>)", field)
+	}
+
+	plainText := true
+	switch outputParam := (*params)["output"]; outputParam {
+	case "text":
+	case "html":
+		plainText = false
+	default:
+		return fmt.Errorf("invalid output parameter %q (must be text or "+
+			"html)", outputParam)
+	}
+
+	maxLengthParam := (*params)["max_length"]
+	maxLength, err := strconv.Atoi(maxLengthParam)
+	if err != nil || maxLength < 0 {
+		return fmt.Errorf("invalid max_length parameter %q", maxLengthParam)
+	}
+
+	m.field = field
+	m.plainText = plainText
+	m.maxLength = maxLength
+	m.ellipsis = (*params)["ellipsis"]
+
+	m.SetReady(true)
+
+	return nil
+}
+
+func (m *SanitizeProcessorModule) Parameters() *base_modules.ParameterMap {
+	return &base_modules.ParameterMap{
+		"field":      "description",
+		"output":     "text",
+		"max_length": "0",
+		"ellipsis":   "…",
+	}
+}
+
+func (m *SanitizeProcessorModule) Duplicate(specificId string) (base_modules.Module, error) {
+	duplicate := NewSanitizeProcessorModule(specificId)
+	err := pipeliner_modules.RegisterPipelinerProcessorModule(duplicate)
+	if err != nil {
+		return nil, err
+	}
+
+	return duplicate, nil
+}
+
+func (m *SanitizeProcessorModule) sanitizeItem(
+	item *datatypes.PipelineItem) (bool, string) {
+	var value string
+	switch m.field {
+	case "name":
+		value = item.GetName()
+	case "description":
+		value = item.GetDescription()
+	default:
+		metadataValue, ok := item.GetMetadata(
+			strings.TrimPrefix(m.field, "metadata."))
+		if !ok {
+			return false, ""
+		}
+		value = expr.ToString(metadataValue)
+	}
+
+	sanitized, err := m.sanitize(value)
+	if err != nil {
+		m.Log(fmt.Errorf("error sanitizing %s : %v", m.field, err))
+		return false, ""
+	}
+
+	if sanitized == value {
+		return false, ""
+	}
+
+	switch m.field {
+	case "name":
+		item.SetName(sanitized)
+	case "description":
+		item.SetDescription(sanitized)
+	default:
+		item.SetMetadata(strings.TrimPrefix(m.field, "metadata."), sanitized)
+	}
+
+	return false, ""
+}
+
+func (m *SanitizeProcessorModule) sanitize(value string) (string, error) {
+	root, err := parseHtmlFragment(value)
+	if err != nil {
+		return "", err
+	}
+
+	if m.plainText {
+		return truncateText(htmlText(root, false), m.maxLength, m.ellipsis),
+			nil
+	}
+
+	return renderHtml(truncateHtml(sanitizeHtml(root), m.maxLength,
+		m.ellipsis))
+}
+
+func init() {
+	pipeliner_modules.RegisterPipelinerProcessorModule(
+		NewSanitizeProcessorModule(""))
+}
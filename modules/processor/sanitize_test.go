@@ -0,0 +1,115 @@
+package input
+
+import (
+	"testing"
+)
+
+func newTestSanitizeModule(t *testing.T, output,
+	maxLength string) *SanitizeProcessorModule {
+	m := NewSanitizeProcessorModule("test")
+
+	params := m.Parameters()
+	(*params)["output"] = output
+	(*params)["max_length"] = maxLength
+
+	if err := m.Configure(params); err != nil {
+		t.Fatalf("Configure : %v", err)
+	}
+
+	return m
+}
+
+func TestSanitizeHtml(t *testing.T) {
+	tests := []struct {
+		html     string
+		expected string
+	}{
+		// Unsafe links.
+		{`<a href="javascript:alert(1)">x</a>`,
+			`<a rel="nofollow noopener noreferrer">x</a>`},
+		{`<a href=" JaVaScRiPt:alert(1)">x</a>`,
+			`<a rel="nofollow noopener noreferrer">x</a>`},
+		{`<a href="https://example.com/a" target="_blank">x</a>`,
+			`<a href="https://example.com/a" ` +
+				`rel="nofollow noopener noreferrer">x</a>`},
+		{`<img src="data:image/gif;base64,AAAA">`, ``},
+
+		// Event handlers and style attributes.
+		{`<a href="/a" onclick="evil()">x</a>`,
+			`<a href="/a" rel="nofollow noopener noreferrer">x</a>`},
+		{`<p onmouseover="evil()" style="color:red" class="c">t</p>`,
+			`<p>t</p>`},
+
+		// Unsafe elements.
+		{`<script>alert(1)</script>ok<style>p{}</style>`, `ok`},
+		{`<iframe src="https://example.com"></iframe><form><input>` +
+			`</form>ok`, `ok`},
+
+		// Tracking pixels.
+		{`<img src="https://example.com/a.gif" width="1" height="1">`, ``},
+		{`<img src="https://example.com/a.gif" width="0px">`, ``},
+		{`<img src="http://feeds.feedburner.com/~r/feed/~4/abc">`, ``},
+		{`<img src="https://stats.wordpress.com/b.gif?x=1">`, ``},
+		{`<img src="https://ad.doubleclick.net/a.gif">`, ``},
+		{`<img>`, ``},
+
+		// Images that are not tracking pixels.
+		{`<img src="https://example.com/tracks/cover.jpg">`,
+			`<img src="https://example.com/tracks/cover.jpg"/>`},
+		{`<img src="https://example.com/pixelart.png" width=300>`,
+			`<img src="https://example.com/pixelart.png" width="300"/>`},
+		{`<img src="http://feeds.feedburner.com/logo.png">`,
+			`<img src="http://feeds.feedburner.com/logo.png"/>`},
+
+		// Safe content is kept.
+		{`<div><b>bold</b> text</div>`, `<div><b>bold</b> text</div>`},
+	}
+
+	m := newTestSanitizeModule(t, "html", "0")
+
+	for _, test := range tests {
+		sanitized, err := m.sanitize(test.html)
+		if err != nil {
+			t.Errorf("sanitize(%q) : %v", test.html, err)
+			continue
+		}
+
+		if sanitized != test.expected {
+			t.Errorf("sanitize(%q) = %q, expected %q", test.html,
+				sanitized, test.expected)
+		}
+	}
+}
+
+func TestSanitizeTruncate(t *testing.T) {
+	tests := []struct {
+		output    string
+		maxLength string
+		html      string
+		expected  string
+	}{
+		{"text", "10", `<p>Hello <b>wonderful</b> world</p>`, "Hello won…"},
+		{"text", "10", `<p>short</p>`, "short"},
+		{"text", "0", `<p>one</p><p>two <script>x</script></p>`,
+			"one\ntwo"},
+		{"html", "10", `<p>Hello <b>wonderful</b> world</p>`,
+			`<p>Hello <b>won…</b></p>`},
+		{"html", "10", `<p>short</p>`, `<p>short</p>`},
+	}
+
+	for _, test := range tests {
+		m := newTestSanitizeModule(t, test.output, test.maxLength)
+
+		sanitized, err := m.sanitize(test.html)
+		if err != nil {
+			t.Errorf("sanitize(%q) : %v", test.html, err)
+			continue
+		}
+
+		if sanitized != test.expected {
+			t.Errorf("sanitize(%q) with output %s and max_length %s = "+
+				"%q, expected %q", test.html, test.output,
+				test.maxLength, sanitized, test.expected)
+		}
+	}
+}
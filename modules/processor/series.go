@@ -0,0 +1,243 @@
+package input
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+	"github.com/brunoga/go-pipeliner/expr"
+	"github.com/brunoga/go-pipeliner/state"
+
+	pipeliner_modules "github.com/brunoga/go-pipeliner/modules"
+	base_modules "gopkg.in/brunoga/go-modules.v1"
+)
+
+// seriesEntry is the saved history for a single episode.
+type seriesEntry struct {
+	Name     string     `json:"name"`
+	Quality  int        `json:"quality"`
+	Accepted time.Time  `json:"accepted"`
+	Upgraded *time.Time `json:"upgraded,omitempty"`
+}
+
+// SeriesProcessorModule lets through only the first release of each series
+// episode, as identified by the metadata set by the episode processor. If an
+// upgrade window is set, later releases of the same episode with a higher
+// quality (resolution, then source, then proper/repack) are also let through
+// until the window, counted from the first release, expires.
+//
+// Accepted releases are remembered between runs if a state store is
+// available. They are only saved once the item was delivered to a consumer,
+// so releases that failed to be handled (for example, by the deluge consumer)
+// or that were dropped by a later processor can be accepted again.
+type SeriesProcessorModule struct {
+	*pipeliner_modules.GenericProcessorModule
+
+	upgradeWindow  time.Duration
+	dropUnparsed   bool
+	stateBucket    *state.Bucket
+	entriesMutex   sync.Mutex
+	pendingEntries map[string]*seriesEntry
+}
+
+func NewSeriesProcessorModule(specificId string) *SeriesProcessorModule {
+	seriesProcessorModule := &SeriesProcessorModule{
+		pipeliner_modules.NewGenericProcessorModule(
+			"Series Processor Module", "1.0.0", "series",
+			specificId, nil),
+		0,
+		false,
+		nil,
+		sync.Mutex{},
+		make(map[string]*seriesEntry),
+	}
+	seriesProcessorModule.SetProcessorFunc(
+		seriesProcessorModule.filterItem)
+
+	return seriesProcessorModule
+}
+
+func (m *SeriesProcessorModule) Configure(params *base_modules.ParameterMap) error {
+	upgradeWindow := time.Duration(0)
+	upgradeWindowParam := (*params)["upgrade_window"]
+	if upgradeWindowParam != "" {
+		var err error
+		upgradeWindow, err = expr.ParseDuration(upgradeWindowParam)
+		if err != nil || upgradeWindow < 0 {
+			return fmt.Errorf("invalid upgrade_window parameter %q",
+				upgradeWindowParam)
+		}
+	}
+
+	dropUnparsed := false
+	switch unparsedParam := (*params)["unparsed"]; unparsedParam {
+	case "", "keep":
+	case "drop":
+		dropUnparsed = true
+	default:
+		return fmt.Errorf("invalid unparsed parameter %q (must be keep "+
+			"or drop)", unparsedParam)
+	}
+
+	m.upgradeWindow = upgradeWindow
+	m.dropUnparsed = dropUnparsed
+
+	m.SetReady(true)
+
+	return nil
+}
+
+func (m *SeriesProcessorModule) Parameters() *base_modules.ParameterMap {
+	return &base_modules.ParameterMap{
+		"upgrade_window": "",
+		"unparsed":       "keep",
+	}
+}
+
+func (m *SeriesProcessorModule) Duplicate(specificId string) (base_modules.Module, error) {
+	duplicate := NewSeriesProcessorModule(specificId)
+	err := pipeliner_modules.RegisterPipelinerProcessorModule(duplicate)
+	if err != nil {
+		return nil, err
+	}
+
+	return duplicate, nil
+}
+
+// SetStateBucket sets the bucket where accepted releases are saved. This
+// satisfies the state.Keeper interface.
+func (m *SeriesProcessorModule) SetStateBucket(stateBucket *state.Bucket) {
+	m.stateBucket = stateBucket
+}
+
+func (m *SeriesProcessorModule) filterItem(
+	item *datatypes.PipelineItem) (bool, string) {
+	series, hasSeries := item.GetMetadataString(MetadataSeries)
+	episodeId, hasEpisodeId := item.GetMetadataString(MetadataEpisodeId)
+	if !hasSeries || !hasEpisodeId || series == "" {
+		return m.dropUnparsed, "item has no series and episode metadata"
+	}
+
+	key := seriesKey(series) + "/" + episodeId
+	quality := itemReleaseQuality(item)
+	now := time.Now()
+
+	m.entriesMutex.Lock()
+	defer m.entriesMutex.Unlock()
+
+	previous, err := m.entry(key)
+	if err != nil {
+		m.Log(err)
+	}
+
+	var accepted *seriesEntry
+	switch {
+	case previous == nil:
+		accepted = &seriesEntry{item.GetName(), quality, now, nil}
+	case m.upgradeWindow == 0:
+		return true, fmt.Sprintf("%s %s already accepted as %q", series,
+			episodeId, previous.Name)
+	case now.Sub(previous.Accepted) > m.upgradeWindow:
+		return true, fmt.Sprintf("%s %s already accepted as %q and the "+
+			"upgrade window expired", series, episodeId, previous.Name)
+	case quality <= previous.Quality:
+		return true, fmt.Sprintf("%s %s already accepted as %q with the "+
+			"same or better quality", series, episodeId, previous.Name)
+	default:
+		accepted = &seriesEntry{item.GetName(), quality, previous.Accepted,
+			&now}
+	}
+
+	// Remember the release for this run right away so duplicates are
+	// dropped, but only save it once the item was handled.
+	m.pendingEntries[key] = accepted
+	item.AddAckHandler(func(err error) {
+		m.resolve(key, accepted, previous, err == nil && item.Delivered())
+	})
+
+	return false, ""
+}
+
+// entry returns the accepted release for the given key, if any. Must be called
+// with the entries mutex held.
+func (m *SeriesProcessorModule) entry(key string) (*seriesEntry, error) {
+	entry, ok := m.pendingEntries[key]
+	if ok {
+		return entry, nil
+	}
+
+	data, ok := m.stateBucket.Get(key)
+	if !ok {
+		return nil, nil
+	}
+
+	entry = &seriesEntry{}
+	err := json.Unmarshal([]byte(data), entry)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding saved entry for %q : %v",
+			key, err)
+	}
+
+	return entry, nil
+}
+
+// resolve saves the accepted release for the given key if the item was
+// delivered or restores the previous one otherwise.
+func (m *SeriesProcessorModule) resolve(key string, accepted,
+	previous *seriesEntry, delivered bool) {
+	m.entriesMutex.Lock()
+	defer m.entriesMutex.Unlock()
+
+	if m.pendingEntries[key] != accepted {
+		// A newer release was accepted after this one.
+		return
+	}
+
+	if !delivered {
+		if previous == nil {
+			delete(m.pendingEntries, key)
+		} else {
+			m.pendingEntries[key] = previous
+		}
+		return
+	}
+
+	data, err := json.Marshal(accepted)
+	if err == nil {
+		err = m.stateBucket.Set(key, string(data))
+	}
+	if err != nil {
+		m.Log(fmt.Errorf("error saving entry for %q : %v", key, err))
+	}
+}
+
+// seriesKey normalizes the given series title so different spellings of it
+// (for example, "Some.Show" and "some show") are considered the same.
+func seriesKey(series string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, series)
+}
+
+// itemReleaseQuality returns the release quality for the given item based on
+// the metadata set by the episode processor.
+func itemReleaseQuality(item *datatypes.PipelineItem) int {
+	resolution, _ := item.GetMetadataString(MetadataResolution)
+	source, _ := item.GetMetadataString(MetadataSource)
+	proper, _ := item.GetMetadata(MetadataProper)
+	repack, _ := item.GetMetadata(MetadataRepack)
+
+	return releaseQuality(resolution, source, proper == true, repack == true)
+}
+
+func init() {
+	pipeliner_modules.RegisterPipelinerProcessorModule(
+		NewSeriesProcessorModule(""))
+}
@@ -0,0 +1,187 @@
+package input
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+	"github.com/brunoga/go-pipeliner/expr"
+
+	pipeliner_modules "github.com/brunoga/go-pipeliner/modules"
+	base_modules "gopkg.in/brunoga/go-modules.v1"
+)
+
+// SortProcessorModule collects items until its input is closed (or until its
+// flush interval expires) and then emits them sorted by a given field,
+// optionally keeping only the first N items. It is registered both as "sort"
+// (no limit by default) and as "top" (a limit is required and items are sorted
+// newest first by default).
+type SortProcessorModule struct {
+	*pipeliner_modules.GenericBufferedProcessorModule
+
+	top bool
+
+	field      *expr.Field
+	descending bool
+	limit      int
+
+	items []*datatypes.PipelineItem
+}
+
+func NewSortProcessorModule(specificId string) *SortProcessorModule {
+	return newSortProcessorModule("Sort Processor Module", "sort",
+		specificId, false)
+}
+
+func NewTopProcessorModule(specificId string) *SortProcessorModule {
+	return newSortProcessorModule("Top Processor Module", "top",
+		specificId, true)
+}
+
+func newSortProcessorModule(name, genericId, specificId string,
+	top bool) *SortProcessorModule {
+	sortProcessorModule := &SortProcessorModule{
+		pipeliner_modules.NewGenericBufferedProcessorModule(name,
+			"1.0.0", genericId, specificId, nil, nil),
+		top,
+		nil,
+		false,
+		0,
+		nil,
+	}
+	sortProcessorModule.SetBufferFunc(sortProcessorModule.bufferItem)
+	sortProcessorModule.SetFlushFunc(sortProcessorModule.sortItems)
+
+	return sortProcessorModule
+}
+
+func (m *SortProcessorModule) Configure(params *base_modules.ParameterMap) error {
+	fieldParam, ok := (*params)["field"]
+	if !ok || fieldParam == "" {
+		return fmt.Errorf("required field parameter not found")
+	}
+
+	field, err := expr.CompileField(fieldParam)
+	if err != nil {
+		return fmt.Errorf("invalid field parameter : %v", err)
+	}
+
+	m.field = field
+
+	orderParam, ok := (*params)["order"]
+	if !ok || orderParam == "" {
+		return fmt.Errorf("required order parameter not found")
+	}
+
+	switch orderParam {
+	case "asc":
+		m.descending = false
+	case "desc":
+		m.descending = true
+	default:
+		return fmt.Errorf("invalid order parameter %q", orderParam)
+	}
+
+	limitParam, ok := (*params)["limit"]
+	if !ok || limitParam == "" {
+		return fmt.Errorf("required limit parameter not found")
+	}
+
+	limit, err := strconv.Atoi(limitParam)
+	if err != nil || limit < 0 {
+		return fmt.Errorf("invalid limit parameter %q", limitParam)
+	}
+
+	if m.top && limit == 0 {
+		return fmt.Errorf("limit parameter must be greater than 0")
+	}
+
+	m.limit = limit
+
+	m.SetReady(true)
+
+	return nil
+}
+
+func (m *SortProcessorModule) Parameters() *base_modules.ParameterMap {
+	if m.top {
+		return &base_modules.ParameterMap{
+			"field": "date",
+			"order": "desc",
+			"limit": "",
+		}
+	}
+
+	return &base_modules.ParameterMap{
+		"field": "date",
+		"order": "asc",
+		"limit": "0",
+	}
+}
+
+func (m *SortProcessorModule) Duplicate(specificId string) (base_modules.Module, error) {
+	duplicate := newSortProcessorModule(m.Name(), m.GenericId(),
+		specificId, m.top)
+	err := pipeliner_modules.RegisterPipelinerProcessorModule(duplicate)
+	if err != nil {
+		return nil, err
+	}
+
+	return duplicate, nil
+}
+
+func (m *SortProcessorModule) bufferItem(
+	item *datatypes.PipelineItem) []*datatypes.PipelineItem {
+	m.items = append(m.items, item)
+
+	return nil
+}
+
+func (m *SortProcessorModule) sortItems(bool) []*datatypes.PipelineItem {
+	items := m.items
+	m.items = nil
+
+	// Items without the field being sorted on always go last.
+	keys := make(map[*datatypes.PipelineItem]interface{}, len(items))
+	for _, item := range items {
+		key := m.field.Value(item)
+		if key == nil {
+			continue
+		}
+		keys[item] = key
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		iKey, iOk := keys[items[i]]
+		jKey, jOk := keys[items[j]]
+		if !iOk || !jOk {
+			return iOk && !jOk
+		}
+
+		result := compareValues(iKey, jKey)
+		if m.descending {
+			return result > 0
+		}
+		return result < 0
+	})
+
+	if m.limit > 0 && len(items) > m.limit {
+		// Items that did not make the cut are done.
+		for _, item := range items[m.limit:] {
+			m.Drop(item, fmt.Sprintf("not in the first %d items",
+				m.limit))
+		}
+
+		items = items[:m.limit]
+	}
+
+	return items
+}
+
+func init() {
+	pipeliner_modules.RegisterPipelinerProcessorModule(
+		NewSortProcessorModule(""))
+	pipeliner_modules.RegisterPipelinerProcessorModule(
+		NewTopProcessorModule(""))
+}
@@ -0,0 +1,239 @@
+package input
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+	"github.com/brunoga/go-pipeliner/expr"
+
+	pipeliner_modules "github.com/brunoga/go-pipeliner/modules"
+	base_modules "gopkg.in/brunoga/go-modules.v1"
+)
+
+// maxIdleThrottleBuckets is the number of per-key buckets above which buckets
+// that are full again (i.e. idle) are discarded.
+const maxIdleThrottleBuckets = 1024
+
+// ThrottleProcessorModule limits the rate at which items go through it with a
+// token bucket that allows bursts of up to burst items and refills at the
+// configured rate. Items are delayed, never dropped. If a key expression (see
+// the expr package) is given, each distinct key value (for example,
+// host(url)) gets its own bucket and items waiting for one key do not hold
+// back items for other keys.
+type ThrottleProcessorModule struct {
+	*pipeliner_modules.GenericDelayedProcessorModule
+
+	interval time.Duration
+	burst    int
+	key      *expr.Expression
+
+	bucketsMutex sync.Mutex
+	buckets      map[string]*tokenBucket
+}
+
+func NewThrottleProcessorModule(specificId string) *ThrottleProcessorModule {
+	throttleProcessorModule := &ThrottleProcessorModule{
+		pipeliner_modules.NewGenericDelayedProcessorModule(
+			"Throttle Processor Module", "1.0.0", "throttle",
+			specificId, nil),
+		0,
+		1,
+		nil,
+		sync.Mutex{},
+		make(map[string]*tokenBucket),
+	}
+	throttleProcessorModule.SetDelayFunc(
+		throttleProcessorModule.throttleItem)
+
+	return throttleProcessorModule
+}
+
+func (m *ThrottleProcessorModule) Configure(params *base_modules.ParameterMap) error {
+	rateParam, ok := (*params)["rate"]
+	if !ok || rateParam == "" {
+		return fmt.Errorf("required rate parameter not found")
+	}
+
+	interval, err := parseRate(rateParam)
+	if err != nil {
+		return fmt.Errorf("invalid rate parameter %q : %v", rateParam,
+			err)
+	}
+
+	burstParam := (*params)["burst"]
+	burst, err := strconv.Atoi(burstParam)
+	if err != nil || burst < 1 {
+		return fmt.Errorf("invalid burst parameter %q (must be a positive "+
+			"integer)", burstParam)
+	}
+
+	var key *expr.Expression
+	keyParam := (*params)["key"]
+	if keyParam != "" {
+		key, err = expr.Compile(keyParam)
+		if err != nil {
+			return fmt.Errorf("invalid key parameter : %v", err)
+		}
+	}
+
+	m.interval = interval
+	m.burst = burst
+	m.key = key
+
+	m.SetReady(true)
+
+	return nil
+}
+
+func (m *ThrottleProcessorModule) Parameters() *base_modules.ParameterMap {
+	return &base_modules.ParameterMap{
+		"rate":  "",
+		"burst": "1",
+		"key":   "",
+	}
+}
+
+func (m *ThrottleProcessorModule) Duplicate(specificId string) (base_modules.Module, error) {
+	duplicate := NewThrottleProcessorModule(specificId)
+	err := pipeliner_modules.RegisterPipelinerProcessorModule(duplicate)
+	if err != nil {
+		return nil, err
+	}
+
+	return duplicate, nil
+}
+
+func (m *ThrottleProcessorModule) throttleItem(
+	item *datatypes.PipelineItem) time.Duration {
+	key := ""
+	if m.key != nil {
+		value, err := m.key.Evaluate(item)
+		if err != nil {
+			// Throttle it together with other items without a key.
+			m.Log(err)
+		} else if value != nil {
+			key = expr.ToString(value)
+		}
+	}
+
+	return m.reserve(key, time.Now())
+}
+
+// reserve takes a token from the bucket for the given key and returns how
+// long the caller must wait before using it.
+func (m *ThrottleProcessorModule) reserve(key string, now time.Time) time.Duration {
+	m.bucketsMutex.Lock()
+	defer m.bucketsMutex.Unlock()
+
+	bucket, ok := m.buckets[key]
+	if !ok {
+		if len(m.buckets) >= maxIdleThrottleBuckets {
+			m.discardIdleBuckets(now)
+		}
+
+		bucket = newTokenBucket(m.interval, m.burst, now)
+		m.buckets[key] = bucket
+	}
+
+	return bucket.reserve(now)
+}
+
+func (m *ThrottleProcessorModule) discardIdleBuckets(now time.Time) {
+	for key, bucket := range m.buckets {
+		if bucket.full(now) {
+			delete(m.buckets, key)
+		}
+	}
+}
+
+// tokenBucket is a token bucket that holds up to burst tokens and gets a new
+// one every interval. Tokens can be reserved in advance, so the bucket level
+// can go below zero.
+type tokenBucket struct {
+	interval time.Duration
+	burst    int
+
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(interval time.Duration, burst int,
+	now time.Time) *tokenBucket {
+	return &tokenBucket{
+		interval,
+		burst,
+		float64(burst),
+		now,
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if now.After(b.last) {
+		b.tokens += float64(now.Sub(b.last)) / float64(b.interval)
+		if b.tokens > float64(b.burst) {
+			b.tokens = float64(b.burst)
+		}
+		b.last = now
+	}
+}
+
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.refill(now)
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens * float64(b.interval))
+}
+
+func (b *tokenBucket) full(now time.Time) bool {
+	b.refill(now)
+
+	return b.tokens >= float64(b.burst)
+}
+
+// parseRate parses rates like "10/s", "30/m", "1/h" or "5/10s" (a count per
+// period, where the period is a duration or just a unit) and returns the
+// interval between items.
+func parseRate(rate string) (time.Duration, error) {
+	parts := strings.SplitN(rate, "/", 2)
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("expected <count>/<period>")
+	}
+
+	count, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil || count <= 0 {
+		return 0, fmt.Errorf("invalid count %q", parts[0])
+	}
+
+	period := strings.TrimSpace(parts[1])
+	if period != "" && (period[0] < '0' || period[0] > '9') {
+		period = "1" + period
+	}
+
+	duration, err := expr.ParseDuration(period)
+	if err != nil {
+		return 0, err
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("period must be positive")
+	}
+
+	interval := time.Duration(float64(duration) / count)
+	if interval <= 0 {
+		return 0, fmt.Errorf("rate is too high")
+	}
+
+	return interval, nil
+}
+
+func init() {
+	pipeliner_modules.RegisterPipelinerProcessorModule(
+		NewThrottleProcessorModule(""))
+}
@@ -0,0 +1,152 @@
+package input
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+)
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bucket := newTokenBucket(100*time.Millisecond, 2, start)
+
+	tests := []struct {
+		elapsed  time.Duration
+		expected time.Duration
+	}{
+		// The burst is available immediately.
+		{0, 0},
+		{0, 0},
+		// Then tokens are reserved in advance.
+		{0, 100 * time.Millisecond},
+		{0, 200 * time.Millisecond},
+		// Refills only pay back the reservations.
+		{250 * time.Millisecond, 50 * time.Millisecond},
+		// Refills are capped at the burst.
+		{10 * time.Second, 0},
+		{10 * time.Second, 0},
+		{10 * time.Second, 100 * time.Millisecond},
+	}
+
+	for i, test := range tests {
+		delay := bucket.reserve(start.Add(test.elapsed))
+		if delay != test.expected {
+			t.Errorf("reserve #%d at +%v = %v, expected %v", i,
+				test.elapsed, delay, test.expected)
+		}
+	}
+
+	if bucket.full(start.Add(10 * time.Second)) {
+		t.Errorf("full right after reserving, expected not full")
+	}
+
+	if !bucket.full(start.Add(11 * time.Second)) {
+		t.Errorf("not full after being idle, expected full")
+	}
+
+	// Time going backwards does not add tokens.
+	for i, expected := range []time.Duration{0, 0, 100 * time.Millisecond} {
+		delay := bucket.reserve(start)
+		if delay != expected {
+			t.Errorf("reserve #%d after time went backwards = %v, "+
+				"expected %v", i, delay, expected)
+		}
+	}
+}
+
+func TestThrottleReserveKeys(t *testing.T) {
+	m := NewThrottleProcessorModule("test")
+	m.interval = time.Second
+	m.burst = 1
+
+	now := time.Now()
+	if delay := m.reserve("a", now); delay != 0 {
+		t.Errorf("first reserve for a = %v, expected 0", delay)
+	}
+	if delay := m.reserve("a", now); delay != time.Second {
+		t.Errorf("second reserve for a = %v, expected 1s", delay)
+	}
+	if delay := m.reserve("b", now); delay != 0 {
+		t.Errorf("first reserve for b = %v, expected 0", delay)
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		rate     string
+		expected time.Duration
+		err      bool
+	}{
+		{"10/s", 100 * time.Millisecond, false},
+		{"30/m", 2 * time.Second, false},
+		{"1/h", time.Hour, false},
+		{"5/10s", 2 * time.Second, false},
+		{"1/2d", 48 * time.Hour, false},
+		{" 2 / s ", 500 * time.Millisecond, false},
+		{"0/s", 0, true},
+		{"-1/s", 0, true},
+		{"10", 0, true},
+		{"10/", 0, true},
+		{"10/x", 0, true},
+		{"a/s", 0, true},
+		{"1/0s", 0, true},
+	}
+
+	for _, test := range tests {
+		interval, err := parseRate(test.rate)
+		if (err != nil) != test.err {
+			t.Errorf("parseRate(%q) : unexpected error %v", test.rate, err)
+			continue
+		}
+
+		if interval != test.expected {
+			t.Errorf("parseRate(%q) = %v, expected %v", test.rate,
+				interval, test.expected)
+		}
+	}
+}
+
+func TestThrottleStopWhileHolding(t *testing.T) {
+	m := NewThrottleProcessorModule("test")
+
+	params := m.Parameters()
+	(*params)["rate"] = "1/h"
+	if err := m.Configure(params); err != nil {
+		t.Fatalf("Configure : %v", err)
+	}
+
+	outputChannel := make(chan *datatypes.PipelineItem)
+	m.SetOutputChannel(outputChannel)
+
+	var waitGroup sync.WaitGroup
+	waitGroup.Add(1)
+	if err := m.Start(&waitGroup); err != nil {
+		t.Fatalf("Start : %v", err)
+	}
+
+	// The first item uses the only token and the second one is held for an
+	// hour.
+	inputChannel := m.GetInputChannel()
+	inputChannel <- datatypes.NewPipelineItem("test")
+	<-outputChannel
+	inputChannel <- datatypes.NewPipelineItem("test")
+
+	m.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		waitGroup.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("module did not stop while holding an item")
+	}
+
+	// Stopping again is harmless.
+	m.Stop()
+}
@@ -0,0 +1,57 @@
+package input
+
+import (
+	"fmt"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+	"github.com/brunoga/go-pipeliner/deadletter"
+
+	pipeliner_modules "github.com/brunoga/go-pipeliner/modules"
+	base_modules "gopkg.in/brunoga/go-modules.v1"
+)
+
+// DeadLetterProducerModule replays items from a dead-letter file (see the
+// dead_letter pipeline option) into a pipeline.
+type DeadLetterProducerModule struct {
+	*itemFileProducerModule
+}
+
+func NewDeadLetterProducerModule(specificId string) *DeadLetterProducerModule {
+	deadLetterProducerModule := &DeadLetterProducerModule{}
+	deadLetterProducerModule.itemFileProducerModule =
+		newItemFileProducerModule("Dead Letter Producer Module",
+			"dead-letter", specificId,
+			deadLetterProducerModule.readDeadLetters)
+
+	return deadLetterProducerModule
+}
+
+func (m *DeadLetterProducerModule) Duplicate(
+	specificId string) (base_modules.Module, error) {
+	duplicate := NewDeadLetterProducerModule(specificId)
+	err := pipeliner_modules.RegisterPipelinerProducerModule(duplicate)
+	if err != nil {
+		return nil, err
+	}
+
+	return duplicate, nil
+}
+
+// readDeadLetters calls itemFunc for the item of each record in the
+// dead-letter file at the given path.
+func (m *DeadLetterProducerModule) readDeadLetters(path string,
+	itemFunc func(*datatypes.PipelineItem) error) error {
+	return deadletter.ReadFile(path, func(record *deadletter.Record) error {
+		if record.Item == nil {
+			m.Log(fmt.Errorf("dead-letter record has no item"))
+			return nil
+		}
+
+		return itemFunc(record.Item)
+	})
+}
+
+func init() {
+	pipeliner_modules.RegisterPipelinerProducerModule(
+		NewDeadLetterProducerModule(""))
+}
@@ -1,10 +1,15 @@
 package input
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"mime"
 	"net/url"
+	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/brunoga/go-pipeliner/datatypes"
 
@@ -12,6 +17,10 @@ import (
 	base_modules "gopkg.in/brunoga/go-modules.v1"
 )
 
+// DirectoryPayloadId is the id of the payload added by the directory producer
+// to all items it creates. The payload is an os.FileInfo.
+const DirectoryPayloadId = "directory"
+
 type DirectoryProducerModule struct {
 	*pipeliner_modules.GenericProducerModule
 
@@ -91,51 +100,140 @@ func (m *DirectoryProducerModule) setupReadDirectory(
 	producerControlChannel <-chan struct{}) {
 	defer close(producerChannel)
 
-	readDirectory(m.GenericId(), m.path, m.recursive, producerChannel,
-		producerControlChannel)
+	// Resume after the last file that was fully handled if the previous run
+	// was interrupted.
+	checkpoint, _ := m.Checkpoint().Get()
+
+	if m.readDirectory(m.path, checkpoint, producerChannel,
+		producerControlChannel) {
+		// All files were produced, so the next run starts from the
+		// beginning once they are all handled.
+		m.Checkpoint().Finish()
+	}
 }
 
-func readDirectory(genericId, path string, recursive bool,
+// readDirectory sends an item for each file in the given path (and, if
+// recursive, in its subdirectories) that comes after the given checkpoint path
+// in traversal order. It returns false if the module was stopped.
+func (m *DirectoryProducerModule) readDirectory(path, checkpoint string,
 	producerChannel chan<- *datatypes.PipelineItem,
-	producerControlChannel <-chan struct{}) {
+	producerControlChannel <-chan struct{}) bool {
 	fileInfos, err := ioutil.ReadDir(path)
 	if err != nil {
-		// TODO(bga): Log error.
-		return
+		m.Log(err)
+		return true
 	}
 
-L:
 	for _, file := range fileInfos {
-		if file.IsDir() && recursive {
-			readDirectory(genericId, filepath.Join(path,
-				file.Name()), true, producerChannel,
-				producerControlChannel)
+		filePath := filepath.Join(path, file.Name())
+		if file.IsDir() && m.recursive {
+			if !m.readDirectory(filePath, checkpoint, producerChannel,
+				producerControlChannel) {
+				return false
+			}
 		} else if !file.IsDir() {
-			fileUrl, err := url.Parse("file://" + filepath.Join(
-				path, file.Name()))
+			if checkpoint != "" && comparePaths(filePath, checkpoint) <= 0 {
+				// Already handled in a previous run.
+				continue
+			}
+
+			fileUrl, err := url.Parse("file://" + filePath)
 			if err != nil {
-				// TODO(bga): Log error.
+				m.Log(err)
 				continue
 			}
 
-			pipelineItem := datatypes.NewPipelineItem(genericId)
+			pipelineItem := datatypes.NewPipelineItem(m.GenericId())
 			_ = pipelineItem.AddUrl(fileUrl)
 			pipelineItem.SetName(fileUrl.Path)
-			pipelineItem.AddPayload("directory", file)
+			pipelineItem.AddPayload(DirectoryPayloadId, file)
+			pipelineItem.SetGuid(fileUrl.String())
+			pipelineItem.SetSize(file.Size())
+			contentType := mime.TypeByExtension(filepath.Ext(filePath))
+			if contentType != "" {
+				pipelineItem.SetContentType(contentType)
+			}
+
+			m.Checkpoint().Track(pipelineItem, filePath)
 
 			select {
 			case _, ok := <-producerControlChannel:
 				if !ok {
-					break L
+					return false
 				}
 			case producerChannel <- pipelineItem:
 				// Do nothing.
 			}
 		}
 	}
+
+	return true
+}
+
+// comparePaths compares the given paths in the same order they are visited by
+// readDirectory (each directory is read in lexical order and subdirectories
+// are visited when found).
+func comparePaths(a, b string) int {
+	aElements := strings.Split(a, string(filepath.Separator))
+	bElements := strings.Split(b, string(filepath.Separator))
+
+	for i := 0; i < len(aElements) && i < len(bElements); i++ {
+		result := strings.Compare(aElements[i], bElements[i])
+		if result != 0 {
+			return result
+		}
+	}
+
+	return len(aElements) - len(bElements)
+}
+
+// fileInfo is the serialized representation of the os.FileInfo payload. It
+// also implements os.FileInfo so decoded payloads can be used in the same way
+// as the original ones.
+type fileInfo struct {
+	FileName    string      `json:"name"`
+	FileSize    int64       `json:"size"`
+	FileMode    os.FileMode `json:"mode"`
+	FileModTime time.Time   `json:"mod_time"`
+}
+
+func (f *fileInfo) Name() string       { return f.FileName }
+func (f *fileInfo) Size() int64        { return f.FileSize }
+func (f *fileInfo) Mode() os.FileMode  { return f.FileMode }
+func (f *fileInfo) ModTime() time.Time { return f.FileModTime }
+func (f *fileInfo) IsDir() bool        { return f.FileMode.IsDir() }
+func (f *fileInfo) Sys() interface{}   { return nil }
+
+// fileInfoCodec is the datatypes.PayloadCodec for the directory payload.
+type fileInfoCodec struct{}
+
+func (c fileInfoCodec) Encode(payload interface{}) ([]byte, error) {
+	info, ok := payload.(os.FileInfo)
+	if !ok {
+		return nil, fmt.Errorf("unexpected payload type %T", payload)
+	}
+
+	return json.Marshal(&fileInfo{
+		info.Name(),
+		info.Size(),
+		info.Mode(),
+		info.ModTime(),
+	})
+}
+
+func (c fileInfoCodec) Decode(data []byte) (interface{}, error) {
+	info := &fileInfo{}
+	err := json.Unmarshal(data, info)
+	if err != nil {
+		return nil, err
+	}
+
+	return info, nil
 }
 
 func init() {
+	datatypes.RegisterPayloadCodec(DirectoryPayloadId, fileInfoCodec{})
+
 	pipeliner_modules.RegisterPipelinerProducerModule(
 		NewDirectoryProducerModule(""))
 }
@@ -0,0 +1,79 @@
+package input
+
+import (
+	"fmt"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+
+	pipeliner_modules "github.com/brunoga/go-pipeliner/modules"
+	base_modules "gopkg.in/brunoga/go-modules.v1"
+)
+
+// errStopped is used to stop reading an item file when the module is stopped.
+var errStopped = fmt.Errorf("stopped")
+
+// itemFileProducerModule is the base of producers that send the items stored
+// in a file (given by the path parameter) into a pipeline, in the order they
+// were written. The readFunc calls itemFunc for each item in the file.
+type itemFileProducerModule struct {
+	*pipeliner_modules.GenericProducerModule
+
+	path     string
+	readFunc func(path string,
+		itemFunc func(*datatypes.PipelineItem) error) error
+}
+
+func newItemFileProducerModule(name, genericId, specificId string,
+	readFunc func(string, func(*datatypes.PipelineItem) error) error) *itemFileProducerModule {
+	itemFileProducerModule := &itemFileProducerModule{
+		pipeliner_modules.NewGenericProducerModule(name, "1.0.0",
+			genericId, specificId, nil),
+		"",
+		readFunc,
+	}
+	itemFileProducerModule.SetProducerFunc(itemFileProducerModule.readItems)
+
+	return itemFileProducerModule
+}
+
+func (m *itemFileProducerModule) Configure(
+	params *base_modules.ParameterMap) error {
+	pathParam, ok := (*params)["path"]
+	if !ok || pathParam == "" {
+		return fmt.Errorf("required path parameter not found")
+	}
+
+	m.path = pathParam
+
+	m.SetReady(true)
+
+	return nil
+}
+
+func (m *itemFileProducerModule) Parameters() *base_modules.ParameterMap {
+	return &base_modules.ParameterMap{
+		"path": "",
+	}
+}
+
+func (m *itemFileProducerModule) readItems(
+	producerChannel chan<- *datatypes.PipelineItem,
+	producerControlChannel <-chan struct{}) {
+	defer close(producerChannel)
+
+	err := m.readFunc(m.path, func(pipelineItem *datatypes.PipelineItem) error {
+		select {
+		case _, ok := <-producerControlChannel:
+			if !ok {
+				return errStopped
+			}
+		case producerChannel <- pipelineItem:
+			// Do nothing.
+		}
+
+		return nil
+	})
+	if err != nil && err != errStopped {
+		m.Log(err)
+	}
+}
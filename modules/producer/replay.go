@@ -0,0 +1,37 @@
+package input
+
+import (
+	"github.com/brunoga/go-pipeliner/recording"
+
+	pipeliner_modules "github.com/brunoga/go-pipeliner/modules"
+	base_modules "gopkg.in/brunoga/go-modules.v1"
+)
+
+// ReplayProducerModule sends items from a recording file (see the record
+// module parameter) into a pipeline, in the order they were recorded.
+type ReplayProducerModule struct {
+	*itemFileProducerModule
+}
+
+func NewReplayProducerModule(specificId string) *ReplayProducerModule {
+	return &ReplayProducerModule{
+		newItemFileProducerModule("Replay Producer Module", "replay",
+			specificId, recording.ReadFile),
+	}
+}
+
+func (m *ReplayProducerModule) Duplicate(
+	specificId string) (base_modules.Module, error) {
+	duplicate := NewReplayProducerModule(specificId)
+	err := pipeliner_modules.RegisterPipelinerProducerModule(duplicate)
+	if err != nil {
+		return nil, err
+	}
+
+	return duplicate, nil
+}
+
+func init() {
+	pipeliner_modules.RegisterPipelinerProducerModule(
+		NewReplayProducerModule(""))
+}
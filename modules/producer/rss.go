@@ -1,20 +1,44 @@
 package input
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/brunoga/go-pipeliner/datatypes"
+	"github.com/brunoga/go-pipeliner/retry"
+	"github.com/brunoga/go-pipeliner/state"
 	"github.com/mmcdole/gofeed"
 
 	pipeliner_modules "github.com/brunoga/go-pipeliner/modules"
 	base_modules "gopkg.in/brunoga/go-modules.v1"
 )
 
+// RssPayloadId is the id of the payload added by the rss producer to all items
+// it creates. The payload is a *gofeed.Item.
+const RssPayloadId = "rss"
+
+// rssUndatedKey is the state bucket key where the GUIDs of handled items
+// without a date are saved.
+const rssUndatedKey = "undated"
+
+// RssProducerModule sends an item for each entry in an RSS or Atom feed. Items
+// with a date are sent oldest first and resumed from the checkpoint. Items
+// without one can't be ordered, so they are sent after them and the ones that
+// were already handled are remembered by GUID instead.
 type RssProducerModule struct {
 	*pipeliner_modules.GenericProducerModule
 
 	rssUrl *url.URL
+
+	stateBucket    *state.Bucket
+	undatedMutex   sync.Mutex
+	handledUndated map[string]bool
 }
 
 func NewRssProducerModule(specificId string) *RssProducerModule {
@@ -22,6 +46,9 @@ func NewRssProducerModule(specificId string) *RssProducerModule {
 		pipeliner_modules.NewGenericProducerModule("RSS Producer Module",
 			"1.0.0", "rss", specificId, nil),
 		nil,
+		nil,
+		sync.Mutex{},
+		nil,
 	}
 	rssProducerModule.SetProducerFunc(rssProducerModule.readRss)
 
@@ -65,37 +92,279 @@ func (m *RssProducerModule) Duplicate(specificId string) (base_modules.Module,
 	return duplicate, nil
 }
 
+// SetStateBucket sets the bucket where handled items without a date are
+// saved. This satisfies the state.Keeper interface.
+func (m *RssProducerModule) SetStateBucket(stateBucket *state.Bucket) {
+	m.stateBucket = stateBucket
+}
+
 func (m *RssProducerModule) readRss(
 	producerChannel chan<- *datatypes.PipelineItem,
 	producerControlChannel <-chan struct{}) {
 	defer close(producerChannel)
 
 	fp := gofeed.NewParser()
-	feed, err := fp.ParseURL(m.rssUrl.String())
+
+	var feed *gofeed.Feed
+	err := m.Retry(func() error {
+		var err error
+		feed, err = fp.ParseURL(m.rssUrl.String())
+		if httpErr, ok := err.(gofeed.HTTPError); ok {
+			// Only server errors and throttling are transient.
+			if httpErr.StatusCode < 500 && httpErr.StatusCode != 429 {
+				return retry.Permanent(err)
+			}
+		}
+
+		return err
+	})
 	if err != nil {
-		// TODO(bga): Handle errors.
+		m.Log(err)
 		return
 	}
 
+	// Items are sent oldest first so checkpoints can be used to resume
+	// after the last item that was fully handled.
+	datedItems := make([]*datatypes.PipelineItem, 0, len(feed.Items))
+	var undatedItems []*datatypes.PipelineItem
 	for _, item := range feed.Items {
 		pipelineItem := datatypes.NewPipelineItem(m.GenericId())
 		pipelineItem.SetName(item.Title)
 		pipelineItem.SetDescription(item.Description)
-		pipelineItem.SetDate(*item.PublishedParsed)
+		if item.PublishedParsed != nil {
+			pipelineItem.SetDate(*item.PublishedParsed)
+		} else if item.UpdatedParsed != nil {
+			pipelineItem.SetDate(*item.UpdatedParsed)
+		}
 		pipelineItem.AddUrlString(item.Link)
-		pipelineItem.AddPayload("rss", item)
-		select {
-		case _, ok := <-producerControlChannel:
-			if !ok {
-				break
+		pipelineItem.AddPayload(RssPayloadId, item)
+		setRssMetadata(pipelineItem, item)
+
+		if pipelineItem.HasDate() {
+			datedItems = append(datedItems, pipelineItem)
+		} else {
+			undatedItems = append(undatedItems, pipelineItem)
+		}
+	}
+
+	sort.SliceStable(datedItems, func(i, j int) bool {
+		return compareRssItems(datedItems[i], datedItems[j]) < 0
+	})
+
+	checkpoint, hasCheckpoint := m.Checkpoint().Get()
+
+	for _, pipelineItem := range datedItems {
+		if hasCheckpoint && compareRssCheckpoint(pipelineItem,
+			checkpoint) <= 0 {
+			// Already handled in a previous run.
+			continue
+		}
+
+		m.Checkpoint().Track(pipelineItem, rssCheckpoint(pipelineItem))
+
+		if !m.send(pipelineItem, producerChannel, producerControlChannel) {
+			return
+		}
+	}
+
+	m.loadHandledUndated(undatedItems)
+
+	for _, pipelineItem := range undatedItems {
+		guid := rssGuid(pipelineItem)
+		if guid != "" {
+			if m.isHandledUndated(guid) {
+				// Already handled in a previous run.
+				continue
 			}
-		case producerChannel <- pipelineItem:
-			// Do nothing.
+
+			pipelineItem.AddAckHandler(func(err error) {
+				if err == nil {
+					m.setHandledUndated(guid)
+				}
+			})
+		}
+
+		if !m.send(pipelineItem, producerChannel, producerControlChannel) {
+			return
 		}
+	}
+}
 
+// send sends the given item to the producer channel. It returns false if the
+// module was stopped.
+func (m *RssProducerModule) send(pipelineItem *datatypes.PipelineItem,
+	producerChannel chan<- *datatypes.PipelineItem,
+	producerControlChannel <-chan struct{}) bool {
+	select {
+	case _, ok := <-producerControlChannel:
+		if !ok {
+			return false
+		}
+	case producerChannel <- pipelineItem:
+		// Do nothing.
 	}
+
+	return true
+}
+
+// loadHandledUndated loads the GUIDs of the handled items without a date.
+// Only the ones still in the feed (i.e. in the given items) are kept, so the
+// saved list does not grow forever.
+func (m *RssProducerModule) loadHandledUndated(
+	undatedItems []*datatypes.PipelineItem) {
+	m.undatedMutex.Lock()
+	defer m.undatedMutex.Unlock()
+
+	m.handledUndated = make(map[string]bool)
+
+	data, ok := m.stateBucket.Get(rssUndatedKey)
+	if !ok {
+		return
+	}
+
+	var guids []string
+	err := json.Unmarshal([]byte(data), &guids)
+	if err != nil {
+		m.Log(fmt.Errorf("error decoding handled items : %v", err))
+		return
+	}
+
+	saved := make(map[string]bool, len(guids))
+	for _, guid := range guids {
+		saved[guid] = true
+	}
+
+	for _, pipelineItem := range undatedItems {
+		guid := rssGuid(pipelineItem)
+		if saved[guid] {
+			m.handledUndated[guid] = true
+		}
+	}
+}
+
+func (m *RssProducerModule) isHandledUndated(guid string) bool {
+	m.undatedMutex.Lock()
+	defer m.undatedMutex.Unlock()
+
+	return m.handledUndated[guid]
+}
+
+// setHandledUndated remembers that the item without a date with the given GUID
+// was handled and saves all handled GUIDs.
+func (m *RssProducerModule) setHandledUndated(guid string) {
+	m.undatedMutex.Lock()
+	defer m.undatedMutex.Unlock()
+
+	m.handledUndated[guid] = true
+
+	guids := make([]string, 0, len(m.handledUndated))
+	for handledGuid := range m.handledUndated {
+		guids = append(guids, handledGuid)
+	}
+	sort.Strings(guids)
+
+	data, err := json.Marshal(guids)
+	if err == nil {
+		err = m.stateBucket.Set(rssUndatedKey, string(data))
+	}
+	if err != nil {
+		m.Log(fmt.Errorf("error saving handled items : %v", err))
+	}
+}
+
+// rssGuid returns the GUID for the given item (or its link if it has no GUID).
+func rssGuid(pipelineItem *datatypes.PipelineItem) string {
+	item, err := datatypes.GetPayloadAs[*gofeed.Item](pipelineItem,
+		RssPayloadId)
+	if err == nil {
+		if item.GUID != "" {
+			return item.GUID
+		}
+		return item.Link
+	}
+
+	return ""
+}
+
+// compareRssItems orders items by date and then by GUID.
+func compareRssItems(a, b *datatypes.PipelineItem) int {
+	switch {
+	case a.GetDate().Before(b.GetDate()):
+		return -1
+	case a.GetDate().After(b.GetDate()):
+		return 1
+	}
+
+	return strings.Compare(rssGuid(a), rssGuid(b))
+}
+
+// rssCheckpoint returns the checkpoint value for the given item. It contains
+// the item date and GUID separated by a space.
+func rssCheckpoint(pipelineItem *datatypes.PipelineItem) string {
+	return pipelineItem.GetDate().UTC().Format(time.RFC3339Nano) + " " +
+		rssGuid(pipelineItem)
+}
+
+// compareRssCheckpoint compares the given item with the given checkpoint in
+// the same way as compareRssItems.
+func compareRssCheckpoint(pipelineItem *datatypes.PipelineItem,
+	checkpoint string) int {
+	checkpointFields := strings.SplitN(checkpoint, " ", 2)
+
+	checkpointDate, err := time.Parse(time.RFC3339Nano, checkpointFields[0])
+	if err != nil {
+		// Invalid checkpoint. Start from scratch.
+		return 1
+	}
+
+	switch {
+	case pipelineItem.GetDate().Before(checkpointDate):
+		return -1
+	case pipelineItem.GetDate().After(checkpointDate):
+		return 1
+	}
+
+	if len(checkpointFields) < 2 {
+		return 0
+	}
+
+	return strings.Compare(rssGuid(pipelineItem), checkpointFields[1])
 }
 
 func init() {
 	pipeliner_modules.RegisterPipelinerProducerModule(NewRssProducerModule(""))
+	datatypes.RegisterPayloadCodec(RssPayloadId,
+		datatypes.NewJSONPayloadCodec(&gofeed.Item{}))
+}
+
+// setRssMetadata populates the well-known metadata fields of the given
+// pipeline item with the relevant information from the given feed item.
+func setRssMetadata(pipelineItem *datatypes.PipelineItem, item *gofeed.Item) {
+	if item.GUID != "" {
+		pipelineItem.SetGuid(item.GUID)
+	}
+
+	if item.Author != nil && item.Author.Name != "" {
+		pipelineItem.SetAuthor(item.Author.Name)
+	} else if len(item.Authors) > 0 && item.Authors[0] != nil &&
+		item.Authors[0].Name != "" {
+		pipelineItem.SetAuthor(item.Authors[0].Name)
+	}
+
+	if len(item.Categories) > 0 {
+		pipelineItem.AddCategories(item.Categories...)
+	}
+
+	// The first enclosure (if any) is usually the actual content.
+	if len(item.Enclosures) > 0 && item.Enclosures[0] != nil {
+		enclosure := item.Enclosures[0]
+		if enclosure.Type != "" {
+			pipelineItem.SetContentType(enclosure.Type)
+		}
+
+		size, err := strconv.ParseInt(enclosure.Length, 10, 64)
+		if err == nil && size > 0 {
+			pipelineItem.SetSize(size)
+		}
+	}
 }
@@ -87,6 +87,8 @@ L:
 		select {
 		case data, ok := <-m.input:
 			if ok {
+				// Each output must acknowledge the item.
+				data.AddPendingAcks(len(m.outputs) - 1)
 				for _, destChan := range m.outputs {
 					destChan <- data
 				}
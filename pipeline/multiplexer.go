@@ -1,7 +1,9 @@
 package pipeline
 
 import (
+	"container/heap"
 	"fmt"
+	"strconv"
 	"sync"
 
 	"github.com/brunoga/go-pipeliner/datatypes"
@@ -10,6 +12,26 @@ import (
 	base_modules "gopkg.in/brunoga/go-modules.v1"
 )
 
+// Merge modes supported by the multiplexer.
+const (
+	// MergeModeAny sends items to the output as soon as they arrive, in
+	// no particular order.
+	MergeModeAny = "any"
+
+	// MergeModeRoundRobin takes one item from each input in turn.
+	MergeModeRoundRobin = "round-robin"
+
+	// MergeModePriority sends all items from an input before moving to
+	// the next one. Inputs are prioritized in the order they were added.
+	MergeModePriority = "priority"
+
+	// MergeModeTimestamp sorts items by date inside a bounded reorder
+	// window.
+	MergeModeTimestamp = "timestamp"
+)
+
+const defaultMergeWindow = 100
+
 type multiplexerModule struct {
 	*base_modules.GenericModule
 
@@ -17,6 +39,9 @@ type multiplexerModule struct {
 	outputChannel chan<- *datatypes.PipelineItem
 	quitChannel   chan struct{}
 	logChannel    chan<- *log.LogEntry
+
+	mode   string
+	window int
 }
 
 func newMultiplexerModule(specificId string) *multiplexerModule {
@@ -27,6 +52,42 @@ func newMultiplexerModule(specificId string) *multiplexerModule {
 		nil,
 		make(chan struct{}),
 		nil,
+		MergeModeAny,
+		defaultMergeWindow,
+	}
+}
+
+func (m *multiplexerModule) Configure(params *base_modules.ParameterMap) error {
+	modeParam, ok := (*params)["mode"]
+	if ok && modeParam != "" {
+		switch modeParam {
+		case MergeModeAny, MergeModeRoundRobin, MergeModePriority,
+			MergeModeTimestamp:
+			m.mode = modeParam
+		default:
+			return fmt.Errorf("invalid merge mode %q", modeParam)
+		}
+	}
+
+	windowParam, ok := (*params)["window"]
+	if ok && windowParam != "" {
+		window, err := strconv.Atoi(windowParam)
+		if err != nil || window < 1 {
+			return fmt.Errorf("invalid merge window %q", windowParam)
+		}
+
+		m.window = window
+	}
+
+	m.SetReady(true)
+
+	return nil
+}
+
+func (m *multiplexerModule) Parameters() *base_modules.ParameterMap {
+	return &base_modules.ParameterMap{
+		"mode":   MergeModeAny,
+		"window": strconv.Itoa(defaultMergeWindow),
 	}
 }
 
@@ -69,7 +130,16 @@ func (m *multiplexerModule) Start(waitGroup *sync.WaitGroup) error {
 		return fmt.Errorf("no input(s) set")
 	}
 
-	go m.doWork(waitGroup)
+	switch m.mode {
+	case MergeModeRoundRobin:
+		go m.doRoundRobinWork(waitGroup)
+	case MergeModePriority:
+		go m.doPriorityWork(waitGroup)
+	case MergeModeTimestamp:
+		go m.doTimestampWork(waitGroup)
+	default:
+		go m.doWork(waitGroup)
+	}
 
 	return nil
 }
@@ -93,6 +163,127 @@ func (m *multiplexerModule) doWork(waitGroup *sync.WaitGroup) {
 	close(m.outputChannel)
 }
 
+func (m *multiplexerModule) doRoundRobinWork(waitGroup *sync.WaitGroup) {
+	defer waitGroup.Done()
+
+	quitChannel := m.quitChannel
+
+	inputChannels := make([]<-chan *datatypes.PipelineItem,
+		len(m.inputChannels))
+	copy(inputChannels, m.inputChannels)
+
+	for len(inputChannels) > 0 {
+		for i := 0; i < len(inputChannels); {
+			select {
+			case data, ok := <-inputChannels[i]:
+				if !ok {
+					// Input is done. Remove it from the rotation.
+					inputChannels = append(inputChannels[:i],
+						inputChannels[i+1:]...)
+					continue
+				}
+
+				select {
+				case m.outputChannel <- data:
+				case <-quitChannel:
+					return
+				}
+			case <-quitChannel:
+				return
+			}
+
+			i++
+		}
+	}
+
+	close(m.outputChannel)
+}
+
+func (m *multiplexerModule) doPriorityWork(waitGroup *sync.WaitGroup) {
+	defer waitGroup.Done()
+
+	quitChannel := m.quitChannel
+
+	for _, inputChannel := range m.inputChannels {
+	L:
+		for {
+			select {
+			case data, ok := <-inputChannel:
+				if !ok {
+					break L
+				}
+
+				select {
+				case m.outputChannel <- data:
+				case <-quitChannel:
+					return
+				}
+			case <-quitChannel:
+				return
+			}
+		}
+	}
+
+	close(m.outputChannel)
+}
+
+func (m *multiplexerModule) doTimestampWork(waitGroup *sync.WaitGroup) {
+	defer waitGroup.Done()
+
+	quitChannel := m.quitChannel
+
+	// Merge all inputs into a single channel and reorder items from it.
+	mergedChannel := make(chan *datatypes.PipelineItem)
+
+	go func() {
+		var wg sync.WaitGroup
+		for _, inputChannel := range m.inputChannels {
+			wg.Add(1)
+			go inputHandler(inputChannel, mergedChannel, quitChannel,
+				&wg)
+		}
+
+		wg.Wait()
+
+		close(mergedChannel)
+	}()
+
+	pending := &itemsByDate{}
+L:
+	for {
+		select {
+		case data, ok := <-mergedChannel:
+			if !ok {
+				break L
+			}
+
+			heap.Push(pending, data)
+			if pending.Len() <= m.window {
+				continue
+			}
+
+			select {
+			case m.outputChannel <- heap.Pop(pending).(*datatypes.PipelineItem):
+			case <-quitChannel:
+				return
+			}
+		case <-quitChannel:
+			return
+		}
+	}
+
+	// Flush pending items.
+	for pending.Len() > 0 {
+		select {
+		case m.outputChannel <- heap.Pop(pending).(*datatypes.PipelineItem):
+		case <-quitChannel:
+			return
+		}
+	}
+
+	close(m.outputChannel)
+}
+
 func inputHandler(inputChannel <-chan *datatypes.PipelineItem,
 	outputChannel chan<- *datatypes.PipelineItem,
 	quitChannel chan struct{}, wg *sync.WaitGroup) {
@@ -102,7 +293,11 @@ L:
 		select {
 		case data, ok := <-inputChannel:
 			if ok {
-				outputChannel <- data
+				select {
+				case outputChannel <- data:
+				case <-quitChannel:
+					break L
+				}
 			} else {
 				break L
 			}
@@ -112,6 +307,34 @@ L:
 	}
 }
 
+// itemsByDate is a min-heap of pipeline items ordered by date. It implements
+// heap.Interface.
+type itemsByDate []*datatypes.PipelineItem
+
+func (h itemsByDate) Len() int {
+	return len(h)
+}
+
+func (h itemsByDate) Less(i, j int) bool {
+	return h[i].GetDate().Before(h[j].GetDate())
+}
+
+func (h itemsByDate) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *itemsByDate) Push(x interface{}) {
+	*h = append(*h, x.(*datatypes.PipelineItem))
+}
+
+func (h *itemsByDate) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[0 : n-1]
+	return item
+}
+
 func init() {
 	base_modules.RegisterModule(newMultiplexerModule(""))
 }
@@ -2,10 +2,15 @@ package pipeline
 
 import (
 	"fmt"
+	"os"
+	"strconv"
 	"sync"
 
 	"github.com/brunoga/go-pipeliner/datatypes"
+	"github.com/brunoga/go-pipeliner/deadletter"
+	"github.com/brunoga/go-pipeliner/explain"
 	"github.com/brunoga/go-pipeliner/log"
+	"github.com/brunoga/go-pipeliner/state"
 
 	base_modules "gopkg.in/brunoga/go-modules.v1"
 )
@@ -58,10 +63,26 @@ type Pipeline struct {
 	multiplexer   *multiplexerModule
 	demultiplexer *demultiplexerModule
 
-	waitGroup    *sync.WaitGroup
-	logWaitGroup *sync.WaitGroup
+	waitGroup           *sync.WaitGroup
+	logWaitGroup        *sync.WaitGroup
+	deadLetterWaitGroup *sync.WaitGroup
+	dropWaitGroup       *sync.WaitGroup
 
-	logChannel chan *log.LogEntry
+	logChannel        chan *log.LogEntry
+	deadLetterChannel chan *deadletter.Entry
+	dropChannel       chan *explain.Entry
+
+	mergeParameters base_modules.ParameterMap
+
+	deadLetterPath string
+
+	stateStore  *state.Store
+	fromScratch bool
+
+	debug bool
+
+	explain     bool
+	explainPath string
 }
 
 func New(name string) *Pipeline {
@@ -75,11 +96,82 @@ func New(name string) *Pipeline {
 		multiplexer:   nil,
 		demultiplexer: nil,
 
-		waitGroup:    nil,
-		logWaitGroup: nil,
+		waitGroup:           nil,
+		logWaitGroup:        nil,
+		deadLetterWaitGroup: nil,
+		dropWaitGroup:       nil,
+
+		logChannel:        make(chan *log.LogEntry),
+		deadLetterChannel: make(chan *deadletter.Entry),
+		dropChannel:       make(chan *explain.Entry),
+
+		mergeParameters: nil,
+
+		deadLetterPath: "",
+
+		stateStore:  nil,
+		fromScratch: false,
+
+		debug: false,
+
+		explain:     false,
+		explainPath: "",
+	}
+}
+
+// SetExplain sets how items dropped from the pipeline are reported once the
+// pipeline is done. If explain is true, they are printed. If path is not
+// empty, they are appended to the file at that path.
+func (p *Pipeline) SetExplain(explain bool, path string) {
+	p.explain = explain
+	p.explainPath = path
+}
+
+// SetDebug enables or disables debug logs (for example, the lineage of items
+// that are dropped or that reach consumers).
+func (p *Pipeline) SetDebug(debug bool) {
+	p.debug = debug
+}
+
+// SetStateStore sets the store used to persist state between runs (for
+// example, producer checkpoints). If fromScratch is true, saved checkpoints and
+// other saved node state are ignored and producers start from the beginning.
+func (p *Pipeline) SetStateStore(stateStore *state.Store, fromScratch bool) {
+	p.stateStore = stateStore
+	p.fromScratch = fromScratch
+}
+
+// SetDeadLetterPath sets the path to the file where items that failed in any
+// of the pipeline nodes are written to. If no path is set, failures are only
+// logged.
+func (p *Pipeline) SetDeadLetterPath(path string) {
+	p.deadLetterPath = path
+}
+
+// SetMergeMode sets how items from multiple producers are merged together.
+// See the MergeMode* constants for the available modes. The window is only
+// used by MergeModeTimestamp and is the maximum number of items that are held
+// for reordering (zero means the default window).
+func (p *Pipeline) SetMergeMode(mode string, window int) error {
+	switch mode {
+	case MergeModeAny, MergeModeRoundRobin, MergeModePriority,
+		MergeModeTimestamp:
+	default:
+		return fmt.Errorf("invalid merge mode %q", mode)
+	}
+
+	if window < 0 {
+		return fmt.Errorf("merge window must not be negative")
+	}
 
-		logChannel: make(chan *log.LogEntry),
+	p.mergeParameters = base_modules.ParameterMap{
+		"mode": mode,
+	}
+	if window > 0 {
+		p.mergeParameters["window"] = strconv.Itoa(window)
 	}
+
+	return nil
 }
 
 func (p *Pipeline) AddProducerNode(producerNode ProducerNode) error {
@@ -93,6 +185,9 @@ func (p *Pipeline) AddProducerNode(producerNode ProducerNode) error {
 	}
 
 	producerNode.SetLogChannel(p.logChannel)
+	p.setDeadLetterChannel(producerNode)
+	p.setDropChannel(producerNode)
+	p.setPipelineName(producerNode)
 
 	p.producerNodes = append(p.producerNodes, producerNode)
 
@@ -105,6 +200,9 @@ func (p *Pipeline) AddProcessorNode(processorNode ProcessorNode) error {
 	}
 
 	processorNode.SetLogChannel(p.logChannel)
+	p.setDeadLetterChannel(processorNode)
+	p.setDropChannel(processorNode)
+	p.setPipelineName(processorNode)
 
 	p.processorNodes = append(p.processorNodes, processorNode)
 
@@ -122,6 +220,9 @@ func (p *Pipeline) AddConsumerNode(consumerNode ConsumerNode) error {
 	}
 
 	consumerNode.SetLogChannel(p.logChannel)
+	p.setDeadLetterChannel(consumerNode)
+	p.setDropChannel(consumerNode)
+	p.setPipelineName(consumerNode)
 
 	p.consumerNodes = append(p.consumerNodes, consumerNode)
 
@@ -139,6 +240,27 @@ func (p *Pipeline) Start() error {
 	p.logWaitGroup.Add(1)
 	go p.logTask()
 
+	// Start dead-letter task.
+	var deadLetterWriter *deadletter.FileWriter
+	if p.deadLetterPath != "" {
+		deadLetterWriter, err = deadletter.NewFileWriter(p.deadLetterPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	p.deadLetterWaitGroup = new(sync.WaitGroup)
+	p.deadLetterWaitGroup.Add(1)
+	go p.deadLetterTask(deadLetterWriter)
+
+	// Start drop task.
+	p.dropWaitGroup = new(sync.WaitGroup)
+	p.dropWaitGroup.Add(1)
+	go p.dropTask()
+
+	p.setupCheckpoints()
+	p.setupStateBuckets()
+
 	p.waitGroup = new(sync.WaitGroup)
 
 	// Start all producers.
@@ -206,6 +328,10 @@ func (p *Pipeline) Stop() {
 
 func (p *Pipeline) Wait() {
 	p.waitGroup.Wait()
+	close(p.deadLetterChannel)
+	p.deadLetterWaitGroup.Wait()
+	close(p.dropChannel)
+	p.dropWaitGroup.Wait()
 	close(p.logChannel)
 	p.logWaitGroup.Wait()
 }
@@ -269,6 +395,7 @@ func (p *Pipeline) connectPipeline() error {
 		p.demultiplexer = module.(*demultiplexerModule)
 
 		p.demultiplexer.SetLogChannel(p.logChannel)
+		p.setDeadLetterChannel(p.demultiplexer)
 
 		// Connect demultiplexer to all consumer nodes.
 		for _, consumerNode := range p.consumerNodes {
@@ -307,6 +434,14 @@ func (p *Pipeline) connectPipeline() error {
 		p.multiplexer = module.(*multiplexerModule)
 
 		p.multiplexer.SetLogChannel(p.logChannel)
+		p.setDeadLetterChannel(p.multiplexer)
+
+		if p.mergeParameters != nil {
+			err = p.multiplexer.Configure(&p.mergeParameters)
+			if err != nil {
+				return err
+			}
+		}
 
 		// Connect multiplexer to last node.
 		p.multiplexer.SetOutputChannel(lastNode.GetInputChannel())
@@ -325,7 +460,143 @@ func (p *Pipeline) connectPipeline() error {
 func (p *Pipeline) logTask() {
 	defer p.logWaitGroup.Done()
 	for logEntry := range p.logChannel {
+		if logEntry.Debug && !p.debug {
+			continue
+		}
+
 		fmt.Printf("%s/%s : %v\n", logEntry.Module.GenericId(),
 			logEntry.Module.SpecificId(), logEntry.Err)
 	}
 }
+
+// setDeadLetterChannel connects the given node to the pipeline dead-letter
+// channel if it can report failed items.
+func (p *Pipeline) setDeadLetterChannel(node interface{}) {
+	reporter, ok := node.(deadletter.Reporter)
+	if ok {
+		reporter.SetDeadLetterChannel(p.deadLetterChannel)
+	}
+}
+
+func (p *Pipeline) deadLetterTask(writer *deadletter.FileWriter) {
+	defer p.deadLetterWaitGroup.Done()
+	if writer != nil {
+		defer writer.Close()
+	}
+	for entry := range p.deadLetterChannel {
+		if writer != nil {
+			err := writer.Write(entry)
+			if err == nil {
+				continue
+			}
+
+			p.logChannel <- log.NewLogEntry(entry.Module,
+				fmt.Errorf("can't write dead letter : %v", err))
+		}
+
+		p.logChannel <- log.NewLogEntry(entry.Module,
+			fmt.Errorf("dead letter %q : %v", entry.Item.GetName(),
+				entry.Err))
+	}
+}
+
+// setupCheckpoints gives a checkpoint to all producer nodes that support it.
+func (p *Pipeline) setupCheckpoints() {
+	if p.stateStore == nil {
+		return
+	}
+
+	for _, producerNode := range p.producerNodes {
+		checkpointer, ok := producerNode.(state.Checkpointer)
+		if !ok {
+			continue
+		}
+
+		key := "checkpoint/" + p.name
+		module, ok := producerNode.(base_modules.Module)
+		if ok {
+			key += "/" + module.GenericId() + "/" + module.SpecificId()
+		}
+
+		checkpointer.SetCheckpoint(state.NewCheckpoint(p.stateStore, key,
+			p.fromScratch))
+	}
+}
+
+// setupStateBuckets gives a state bucket to all nodes that keep their own
+// state between runs.
+func (p *Pipeline) setupStateBuckets() {
+	if p.stateStore == nil {
+		return
+	}
+
+	var nodes []interface{}
+	for _, producerNode := range p.producerNodes {
+		nodes = append(nodes, producerNode)
+	}
+	for _, processorNode := range p.processorNodes {
+		nodes = append(nodes, processorNode)
+	}
+	for _, consumerNode := range p.consumerNodes {
+		nodes = append(nodes, consumerNode)
+	}
+
+	for _, node := range nodes {
+		keeper, ok := node.(state.Keeper)
+		if !ok {
+			continue
+		}
+
+		prefix := "state/" + p.name + "/"
+		module, ok := node.(base_modules.Module)
+		if ok {
+			prefix += module.GenericId() + "/" + module.SpecificId() + "/"
+		}
+
+		keeper.SetStateBucket(state.NewBucket(p.stateStore, prefix,
+			p.fromScratch))
+	}
+}
+
+// setPipelineName tells the given node which pipeline it is part of, if it
+// wants to know.
+func (p *Pipeline) setPipelineName(node interface{}) {
+	setter, ok := node.(interface {
+		SetPipelineName(string)
+	})
+	if ok {
+		setter.SetPipelineName(p.name)
+	}
+}
+
+// setDropChannel connects the given node to the pipeline drop channel if it can
+// report dropped items.
+func (p *Pipeline) setDropChannel(node interface{}) {
+	reporter, ok := node.(explain.Reporter)
+	if ok {
+		reporter.SetDropChannel(p.dropChannel)
+	}
+}
+
+func (p *Pipeline) dropTask() {
+	defer p.dropWaitGroup.Done()
+
+	var entries []*explain.Entry
+	for entry := range p.dropChannel {
+		if p.explain || p.explainPath != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	if p.explain {
+		explain.WriteText(os.Stdout, p.name, entries)
+	}
+
+	if p.explainPath != "" {
+		err := explain.AppendFile(p.explainPath, p.name, entries)
+		if err != nil {
+			fmt.Printf("can't write dropped items to %q : %v\n",
+				p.explainPath, err)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package recording
+
+import (
+	"github.com/brunoga/go-pipeliner/datatypes"
+	"github.com/brunoga/go-pipeliner/jsonlines"
+)
+
+// Writer writes pipeline items to a recording file, one JSON encoded item per
+// line.
+type Writer struct {
+	writer *jsonlines.Writer
+}
+
+// NewWriter creates (or truncates) the recording file at the given path.
+func NewWriter(path string) (*Writer, error) {
+	writer, err := jsonlines.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Writer{writer}, nil
+}
+
+// Write appends the given item to the recording.
+func (w *Writer) Write(item *datatypes.PipelineItem) error {
+	return w.writer.Write(item)
+}
+
+// Close closes the underlying file.
+func (w *Writer) Close() error {
+	return w.writer.Close()
+}
+
+// ReadFile calls itemFunc for each item in the recording file at the given
+// path, in the order they were recorded. It stops at the first error returned
+// by itemFunc.
+func ReadFile(path string, itemFunc func(*datatypes.PipelineItem) error) error {
+	return jsonlines.ReadFile(path, func() interface{} {
+		return &datatypes.PipelineItem{}
+	}, func(value interface{}) error {
+		return itemFunc(value.(*datatypes.PipelineItem))
+	})
+}
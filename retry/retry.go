@@ -0,0 +1,166 @@
+package retry
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"net/textproto"
+	"time"
+)
+
+// Policy describes how an operation that failed should be retried.
+type Policy struct {
+	// MaxAttempts is the maximum number of times an operation is tried
+	// (including the first time). Values smaller than 1 are handled as 1.
+	MaxAttempts int
+
+	// InitialBackoff is the time to wait before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the maximum time to wait between retries. Zero means
+	// no limit.
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor the backoff is multiplied by after each
+	// retry.
+	Multiplier float64
+
+	// Jitter is the fraction (between 0 and 1) of the backoff that is
+	// randomized to avoid retrying in lockstep.
+	Jitter float64
+
+	// Retryable decides if the given error should be retried. If nil,
+	// IsRetryable is used.
+	Retryable func(error) bool
+}
+
+// NewPolicy returns a new Policy with sensible defaults that does no retries
+// (MaxAttempts is 1).
+func NewPolicy() *Policy {
+	return &Policy{
+		MaxAttempts:    1,
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+		Multiplier:     2,
+		Jitter:         0.2,
+		Retryable:      nil,
+	}
+}
+
+// Validate returns a non-nil error if the policy has invalid values.
+func (p *Policy) Validate() error {
+	if p.InitialBackoff < 0 || p.MaxBackoff < 0 {
+		return fmt.Errorf("backoff must not be negative")
+	}
+
+	if p.Multiplier < 1 {
+		return fmt.Errorf("multiplier must be at least 1")
+	}
+
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return fmt.Errorf("jitter must be between 0 and 1")
+	}
+
+	return nil
+}
+
+// Backoff returns the time to wait before the given retry (starting at 1).
+func (p *Policy) Backoff(retry int) time.Duration {
+	backoff := float64(p.InitialBackoff) *
+		math.Pow(p.Multiplier, float64(retry-1))
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		backoff += backoff * p.Jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(backoff)
+}
+
+// Do calls operation until it succeeds, returns an error that is not
+// retryable or the maximum number of attempts is reached. It returns the last
+// error returned by operation (unwrapped if it was marked as permanent). If
+// quitChannel is closed while waiting for a retry, Do returns immediately.
+func (p *Policy) Do(quitChannel <-chan struct{}, operation func() error) error {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = operation()
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= p.MaxAttempts || !retryable(err) {
+			break
+		}
+
+		timer := time.NewTimer(p.Backoff(attempt))
+		select {
+		case <-timer.C:
+		case <-quitChannel:
+			timer.Stop()
+			return unwrap(err)
+		}
+	}
+
+	return unwrap(err)
+}
+
+// permanentError wraps errors that must not be retried.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+// Permanent marks the given error as not retryable.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{err}
+}
+
+// IsRetryable is the default error classification. Errors marked with
+// Permanent and SMTP permanent failures (5xx codes) are not retryable. Network
+// errors are retryable if they are timeouts or temporary. Everything else is
+// retryable.
+func IsRetryable(err error) bool {
+	switch typedErr := err.(type) {
+	case *permanentError:
+		return false
+	case *textproto.Error:
+		return typedErr.Code < 500
+	case net.Error:
+		return typedErr.Timeout() || isTemporary(typedErr)
+	}
+
+	return true
+}
+
+func isTemporary(err error) bool {
+	temporary, ok := err.(interface {
+		Temporary() bool
+	})
+
+	return ok && temporary.Temporary()
+}
+
+func unwrap(err error) error {
+	permanentErr, ok := err.(*permanentError)
+	if ok {
+		return permanentErr.err
+	}
+
+	return err
+}
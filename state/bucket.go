@@ -0,0 +1,83 @@
+package state
+
+import (
+	"sync"
+)
+
+// Keeper is implemented by nodes that keep their own state between runs.
+type Keeper interface {
+	SetStateBucket(*Bucket)
+}
+
+// Bucket is a view of a Store where all keys share a prefix. It is used by
+// nodes to keep their own state between runs.
+//
+// All methods can be called on a nil Bucket, in which case nothing is saved
+// or restored.
+type Bucket struct {
+	mutex sync.Mutex
+
+	store       *Store
+	prefix      string
+	ignoreSaved bool
+
+	written map[string]bool
+}
+
+// NewBucket returns a new bucket for keys with the given prefix in the given
+// store. If ignoreSaved is true, values saved in previous runs are ignored
+// (but new values are still saved).
+func NewBucket(store *Store, prefix string, ignoreSaved bool) *Bucket {
+	return &Bucket{
+		store:       store,
+		prefix:      prefix,
+		ignoreSaved: ignoreSaved,
+		written:     make(map[string]bool),
+	}
+}
+
+// Get returns the value associated with the given key and true if it exists or
+// an empty string and false otherwise.
+func (b *Bucket) Get(key string) (string, bool) {
+	if b == nil {
+		return "", false
+	}
+
+	b.mutex.Lock()
+	ignored := b.ignoreSaved && !b.written[key]
+	b.mutex.Unlock()
+
+	if ignored {
+		return "", false
+	}
+
+	return b.store.Get(b.prefix + key)
+}
+
+// Set associates the given value with the given key and saves it. It returns a
+// nil error on success or a non-nil error on failure.
+func (b *Bucket) Set(key, value string) error {
+	if b == nil {
+		return nil
+	}
+
+	b.mutex.Lock()
+	b.written[key] = true
+	b.mutex.Unlock()
+
+	return b.store.Set(b.prefix+key, value)
+}
+
+// Delete removes the given key and saves the change. It returns a nil error on
+// success or a non-nil error on failure.
+func (b *Bucket) Delete(key string) error {
+	if b == nil {
+		return nil
+	}
+
+	b.mutex.Lock()
+	delete(b.written, key)
+	b.mutex.Unlock()
+
+	return b.store.Delete(b.prefix + key)
+}
@@ -0,0 +1,163 @@
+package state
+
+import (
+	"sync"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+)
+
+// Checkpointer is implemented by nodes that can resume their work from a
+// checkpoint.
+type Checkpointer interface {
+	SetCheckpoint(*Checkpoint)
+}
+
+// Checkpoint records how far a producer got, so a new run can resume after
+// the last item that was fully handled. Items are tracked in the order they
+// were produced and the checkpoint only advances when all items up to a given
+// one were acknowledged. If any item fails (see PipelineItem.Nack), the
+// checkpoint stops advancing for the current run so the failed item is
+// produced again in the next one. Producers that only want to resume
+// interrupted runs call Finish once they tracked all items, so the checkpoint
+// is removed when the run completes successfully.
+//
+// All methods can be called on a nil Checkpoint, in which case nothing is
+// saved or restored.
+type Checkpoint struct {
+	mutex sync.Mutex
+
+	store       *Store
+	key         string
+	ignoreSaved bool
+
+	next      uint64
+	committed uint64
+	done      map[uint64]string
+	failed    bool
+	finished  bool
+	err       error
+}
+
+// NewCheckpoint returns a new checkpoint saved with the given key in the given
+// store. If ignoreSaved is true, any previously saved checkpoint is ignored
+// (but new checkpoints are still saved).
+func NewCheckpoint(store *Store, key string, ignoreSaved bool) *Checkpoint {
+	return &Checkpoint{
+		store:       store,
+		key:         key,
+		ignoreSaved: ignoreSaved,
+		done:        make(map[uint64]string),
+	}
+}
+
+// Get returns the saved checkpoint value and true if there is one or an empty
+// string and false otherwise.
+func (c *Checkpoint) Get() (string, bool) {
+	if c == nil || c.ignoreSaved {
+		return "", false
+	}
+
+	return c.store.Get(c.key)
+}
+
+// Track registers the given item as the next produced item. The given value
+// is saved as the checkpoint once this item and all items tracked before it
+// were acknowledged.
+func (c *Checkpoint) Track(item *datatypes.PipelineItem, value string) {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	sequence := c.next
+	c.next++
+	c.mutex.Unlock()
+
+	item.AddAckHandler(func(err error) {
+		c.resolve(sequence, value, err)
+	})
+}
+
+// Finish marks the end of the run, after all items were tracked. Once all of
+// them were acknowledged successfully, the saved checkpoint is removed so the
+// next run starts from the beginning. It must not be called if the run was
+// interrupted, so the next run resumes after the last handled item instead.
+func (c *Checkpoint) Finish() {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.finished = true
+
+	c.removeIfComplete()
+}
+
+// Err returns the last error that happened while saving the checkpoint, if
+// any.
+func (c *Checkpoint) Err() error {
+	if c == nil {
+		return nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.err
+}
+
+func (c *Checkpoint) resolve(sequence uint64, value string, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err != nil {
+		c.failed = true
+	}
+
+	if c.failed {
+		return
+	}
+
+	c.done[sequence] = value
+
+	advanced := false
+	for {
+		doneValue, ok := c.done[c.committed]
+		if !ok {
+			break
+		}
+
+		delete(c.done, c.committed)
+		c.committed++
+
+		value = doneValue
+		advanced = true
+	}
+
+	if c.removeIfComplete() || !advanced {
+		return
+	}
+
+	err = c.store.Set(c.key, value)
+	if err != nil {
+		c.err = err
+	}
+}
+
+// removeIfComplete removes the saved checkpoint if the run finished and all
+// items were acknowledged successfully. It returns true if the checkpoint was
+// removed. Must be called with the mutex held.
+func (c *Checkpoint) removeIfComplete() bool {
+	if !c.finished || c.failed || c.committed != c.next {
+		return false
+	}
+
+	err := c.store.Delete(c.key)
+	if err != nil {
+		c.err = err
+	}
+
+	return true
+}
@@ -0,0 +1,101 @@
+package state
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/brunoga/go-pipeliner/datatypes"
+)
+
+func newTestStore(t *testing.T) *Store {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatalf("TempDir : %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	store, err := OpenStore(filepath.Join(dir, "state.json"))
+	if err != nil {
+		t.Fatalf("OpenStore : %v", err)
+	}
+
+	return store
+}
+
+func trackItems(checkpoint *Checkpoint,
+	values ...string) []*datatypes.PipelineItem {
+	items := make([]*datatypes.PipelineItem, len(values))
+	for i, value := range values {
+		items[i] = datatypes.NewPipelineItem("test")
+		checkpoint.Track(items[i], value)
+	}
+
+	return items
+}
+
+func TestCheckpointOrder(t *testing.T) {
+	store := newTestStore(t)
+	checkpoint := NewCheckpoint(store, "key", false)
+
+	items := trackItems(checkpoint, "a", "b", "c")
+
+	// Acknowledging out of order only advances up to the first pending item.
+	items[1].Ack()
+	if value, ok := checkpoint.Get(); ok {
+		t.Errorf("checkpoint %q before the first item was acknowledged",
+			value)
+	}
+
+	items[0].Ack()
+	if value, _ := checkpoint.Get(); value != "b" {
+		t.Errorf("checkpoint %q, expected \"b\"", value)
+	}
+
+	// Failures stop the checkpoint for the current run.
+	items[2].Nack(fmt.Errorf("failed"))
+	if value, _ := checkpoint.Get(); value != "b" {
+		t.Errorf("checkpoint %q after a failure, expected \"b\"", value)
+	}
+}
+
+func TestCheckpointFinish(t *testing.T) {
+	store := newTestStore(t)
+	checkpoint := NewCheckpoint(store, "key", false)
+
+	items := trackItems(checkpoint, "a", "b")
+	checkpoint.Finish()
+
+	items[0].Ack()
+	if value, _ := checkpoint.Get(); value != "a" {
+		t.Errorf("checkpoint %q, expected \"a\"", value)
+	}
+
+	// Once all items of a finished run were handled, the next run starts
+	// from the beginning.
+	items[1].Ack()
+	if value, ok := checkpoint.Get(); ok {
+		t.Errorf("checkpoint %q after the run completed", value)
+	}
+
+	// An interrupted run is resumed.
+	checkpoint = NewCheckpoint(store, "key", false)
+	items = trackItems(checkpoint, "a", "b")
+	items[0].Ack()
+
+	checkpoint = NewCheckpoint(store, "key", false)
+	if value, _ := checkpoint.Get(); value != "a" {
+		t.Errorf("checkpoint %q after an interrupted run, expected \"a\"",
+			value)
+	}
+
+	// A finished run with failures is resumed too.
+	items = trackItems(checkpoint, "b")
+	checkpoint.Finish()
+	items[0].Nack(fmt.Errorf("failed"))
+	if value, _ := checkpoint.Get(); value != "a" {
+		t.Errorf("checkpoint %q after a failed run, expected \"a\"", value)
+	}
+}
@@ -0,0 +1,183 @@
+package state
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// flushDelay is how long changes are kept in memory before the store is
+// written to disk, so bursts of changes result in a single write.
+const flushDelay = time.Second
+
+// Store is a persistent key/value store backed by a JSON file. Changes are
+// written to disk at most once every flushDelay and when the store is closed.
+// It is safe for concurrent use.
+type Store struct {
+	mutex sync.Mutex
+
+	path   string
+	values map[string]string
+
+	dirty  bool
+	timer  *time.Timer
+	closed bool
+	err    error
+}
+
+// OpenStore opens the store at the given path. If the file does not exist, an
+// empty store is returned and the file is created on the first change.
+func OpenStore(path string) (*Store, error) {
+	store := &Store{
+		path:   path,
+		values: make(map[string]string),
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &store.values)
+	if err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Get returns the value associated with the given key and true if it exists or
+// an empty string and false otherwise.
+func (s *Store) Get(key string) (string, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	value, ok := s.values[key]
+
+	return value, ok
+}
+
+// Set associates the given value with the given key and schedules the store
+// to be saved. It returns the error of the last failed save, if any.
+func (s *Store) Set(key, value string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if current, ok := s.values[key]; ok && current == value {
+		return s.err
+	}
+
+	s.values[key] = value
+
+	return s.changed()
+}
+
+// Delete removes the given key from the store and schedules the store to be
+// saved. It returns the error of the last failed save, if any.
+func (s *Store) Delete(key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.values[key]; !ok {
+		return s.err
+	}
+
+	delete(s.values, key)
+
+	return s.changed()
+}
+
+// Flush writes any pending changes to disk. It returns a nil error on success
+// or a non-nil error on failure.
+func (s *Store) Flush() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.flush()
+}
+
+// Close writes any pending changes to disk. Changes made after the store was
+// closed are written immediately. It returns a nil error on success or a
+// non-nil error on failure.
+func (s *Store) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.closed = true
+
+	return s.flush()
+}
+
+// changed marks the store as dirty and schedules a flush (or flushes it
+// immediately if it was closed). Must be called with the mutex held.
+func (s *Store) changed() error {
+	s.dirty = true
+
+	if s.closed {
+		return s.flush()
+	}
+
+	if s.timer == nil {
+		s.timer = time.AfterFunc(flushDelay, func() {
+			s.Flush()
+		})
+	}
+
+	return s.err
+}
+
+// flush saves the store if it is dirty. Must be called with the mutex held.
+func (s *Store) flush() error {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+
+	if !s.dirty {
+		return s.err
+	}
+
+	s.err = s.save()
+	if s.err == nil {
+		s.dirty = false
+	}
+
+	return s.err
+}
+
+// save writes the store to disk. The file is replaced atomically so a crash
+// never leaves a partially written store behind. Must be called with the
+// mutex held.
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.values, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tempFile, err := ioutil.TempFile(filepath.Dir(s.path),
+		filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = tempFile.Write(data)
+	if err == nil {
+		err = tempFile.Sync()
+	}
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tempFile.Name())
+		return err
+	}
+
+	return os.Rename(tempFile.Name(), s.path)
+}
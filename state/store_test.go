@@ -0,0 +1,51 @@
+package state
+
+import (
+	"os"
+	"testing"
+)
+
+func TestStoreFlush(t *testing.T) {
+	store := newTestStore(t)
+
+	store.Set("a", "1")
+	store.Set("b", "2")
+	store.Delete("b")
+
+	// Changes are kept in memory until the store is flushed.
+	if _, err := os.Stat(store.path); !os.IsNotExist(err) {
+		t.Errorf("store written before being flushed : %v", err)
+	}
+
+	if err := store.Flush(); err != nil {
+		t.Fatalf("Flush : %v", err)
+	}
+
+	store.Set("c", "3")
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close : %v", err)
+	}
+
+	reopened, err := OpenStore(store.path)
+	if err != nil {
+		t.Fatalf("OpenStore : %v", err)
+	}
+
+	if len(reopened.values) != 2 || reopened.values["a"] != "1" ||
+		reopened.values["c"] != "3" {
+		t.Errorf("reopened store values = %v, expected a=1 and c=3",
+			reopened.values)
+	}
+
+	// Changes after the store was closed are written immediately.
+	store.Set("d", "4")
+
+	reopened, err = OpenStore(store.path)
+	if err != nil {
+		t.Fatalf("OpenStore : %v", err)
+	}
+
+	if reopened.values["d"] != "4" {
+		t.Errorf("change after Close not saved : %v", reopened.values)
+	}
+}